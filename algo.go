@@ -13,16 +13,120 @@ var (
 	algosMutex sync.RWMutex
 )
 
+// AlgoPlugin - an algorithm stepped through its input a byte at a time
+// A plugin reports a bad input or state by returning an error, which fails
+// only the one session using it. See LegacyAlgoPlugin for plugins written
+// before the methods returned errors.
 type AlgoPlugin interface {
 	Name() string
-	Init(inputLen int)
-	Step(d byte)
-	SerializeState() string
+	Init(inputLen int) error
+	Step(d byte) error
+	SerializeState() (string, error)
 	DeserializeState(state string) error
+
+	// Done reports whether the algorithm needs no more steps
+	Done() bool
+	// Output returns the algorithm's output so far, which is final once Done
+	Output() (string, error)
 }
 
 type AlgoFactory func() AlgoPlugin
 
+// AlgoInfo - metadata about an algorithm, served to the front end
+type AlgoInfo struct {
+	Name        string           `json:"name"`
+	DisplayName string           `json:"display_name"`
+	Description string           `json:"description,omitempty"`
+	Reference   string           `json:"reference,omitempty"`
+	Input       InputConstraints `json:"input"`
+	Example     string           `json:"example,omitempty"`
+
+	// Version changes whenever the states or output of the plugin would, for
+	// the same input, so that traces recorded with another version are rejected
+	Version string `json:"version,omitempty"`
+}
+
+// InputConstraints - what input the algorithm accepts
+// a MaxLength of 0 means the length is unbounded
+type InputConstraints struct {
+	MaxLength int  `json:"max_length,omitempty"`
+	Binary    bool `json:"binary"`
+}
+
+// Positioner is optionally implemented by an AlgoPlugin that makes more than
+// one pass over its input, to report the index of the input byte it needs next.
+// A position equal to the input length asks for one more step to complete the
+// pass, which is stepped with a zero byte. Beyond that no more input is needed.
+type Positioner interface {
+	Position() int
+}
+
+// PositionOf returns the Position of a plugin that is a Positioner,
+// ok is false if it is not
+func PositionOf(algo AlgoPlugin) (pos int, ok bool) {
+	p, ok := unwrap(algo).(Positioner)
+	if !ok {
+		return 0, false
+	}
+	return p.Position(), true
+}
+
+// Cursor returns the index of the input byte a plugin stepped steps times since
+// init consumes next, its Position if it is a Positioner
+func Cursor(algo AlgoPlugin, steps int) int {
+	if pos, ok := PositionOf(algo); ok {
+		return pos
+	}
+	return steps
+}
+
+// NextInput returns the byte of input a plugin stepped steps times since init
+// is stepped with next, ok is false once it is done or needs no more input
+func NextInput(algo AlgoPlugin, input []byte, steps int) (d byte, ok bool) {
+	if algo.Done() {
+		return 0, false
+	}
+
+	pos := Cursor(algo, steps)
+	switch {
+	case pos < len(input):
+		return input[pos], true
+	case pos == len(input):
+		// only a Positioner asks for a step to complete its pass
+		_, ok := PositionOf(algo)
+		return 0, ok
+	default:
+		return 0, false
+	}
+}
+
+// NamedOutputter is optionally implemented by an AlgoPlugin whose Output is
+// made of parts worth showing apart, e.g. the block size and both signatures
+// of an ssdeep hash
+type NamedOutputter interface {
+	NamedOutputs() (map[string]string, error)
+}
+
+// NamedOutputsOf returns the NamedOutputs of a plugin that is a
+// NamedOutputter, outputs is nil if it is not
+func NamedOutputsOf(algo AlgoPlugin) (outputs map[string]string, err error) {
+	n, ok := unwrap(algo).(NamedOutputter)
+	if !ok {
+		return nil, nil
+	}
+
+	err = protect(algo.Name(), func() error {
+		outputs, err = n.NamedOutputs()
+		return err
+	})
+	return outputs, err
+}
+
+// Describer is optionally implemented by an AlgoPlugin to supply its AlgoInfo
+type Describer interface {
+	Describe() AlgoInfo
+}
+
 func Register(algoFactory AlgoFactory) {
 	algosMutex.Lock()
 	defer algosMutex.Unlock()
@@ -33,6 +137,8 @@ func Register(algoFactory AlgoFactory) {
 	algos[algoFactory().Name()] = algoFactory
 }
 
+// GetAlgo returns a new instance of the named algorithm, guarded so that a
+// panic in any of its methods is returned as a *PanicError
 func GetAlgo(name string) (AlgoPlugin, error) {
 	algosMutex.RLock()
 	defer algosMutex.RUnlock()
@@ -40,7 +146,7 @@ func GetAlgo(name string) (AlgoPlugin, error) {
 	if !ok {
 		return nil, fmt.Errorf("algo not registered: %s", name)
 	}
-	return m(), nil
+	return guard(m()), nil
 }
 
 // Algos returns the names of all registered algorithms
@@ -57,6 +163,41 @@ func Algos() []string {
 	return names
 }
 
+// Describe returns the AlgoInfo for a plugin, if the plugin is not a
+// Describer, or its Describe panics, only its name is filled in
+func Describe(algo AlgoPlugin) AlgoInfo {
+	var info AlgoInfo
+	if d, ok := unwrap(algo).(Describer); ok {
+		err := protect(algo.Name(), func() error {
+			info = d.Describe()
+			return nil
+		})
+		if err != nil {
+			info = AlgoInfo{}
+		}
+	}
+
+	info.Name = algo.Name()
+	if len(info.DisplayName) < 1 {
+		info.DisplayName = info.Name
+	}
+	return info
+}
+
+// AlgoInfos returns the AlgoInfo of all registered algorithms, sorted by name
+func AlgoInfos() []AlgoInfo {
+	algosMutex.RLock()
+	defer algosMutex.RUnlock()
+
+	infos := make([]AlgoInfo, 0, len(algos))
+	for _, factory := range algos {
+		infos = append(infos, Describe(factory()))
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
 func StrictUnmarshalJSON(data *io.Reader, v interface{}) error {
 	dec := json.NewDecoder(*data)
 	dec.DisallowUnknownFields()
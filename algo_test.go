@@ -1,6 +1,7 @@
 package algoexplore
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -9,10 +10,12 @@ type Fake struct {
 }
 
 func (fake *Fake) Name() string                        { return "fake" }
-func (fake *Fake) Init(inputLen int)                   { return }
-func (fake *Fake) Step(d byte)                         { return }
-func (fake *Fake) SerializeState() string              { return "serialized" }
+func (fake *Fake) Init(inputLen int) error             { return nil }
+func (fake *Fake) Step(d byte) error                   { return nil }
+func (fake *Fake) SerializeState() (string, error)     { return "serialized", nil }
 func (fake *Fake) DeserializeState(state string) error { return nil }
+func (fake *Fake) Done() bool                          { return false }
+func (fake *Fake) Output() (string, error)             { return "", nil }
 
 func TestRegister_withValidFactory_addsToRegistry(t *testing.T) {
 	Register(func() AlgoPlugin { return &Fake{} })
@@ -36,3 +39,119 @@ func TestRegister_twiceWithSamePluginName_Panics(t *testing.T) {
 
 	t.Errorf("did not panic on duplicate registration")
 }
+
+type DescribedFake struct{ Fake }
+
+func (fake *DescribedFake) Name() string { return "described" }
+func (fake *DescribedFake) Describe() AlgoInfo {
+	return AlgoInfo{
+		Name:        "ignored",
+		DisplayName: "Described Fake",
+		Input:       InputConstraints{MaxLength: 16, Binary: true},
+	}
+}
+
+func TestDescribe_withDescriber_UsesItsMetadata(t *testing.T) {
+	info := Describe(&DescribedFake{})
+
+	if info.Name != "described" {
+		t.Fatalf("expected the plugin's Name() to win, got %s", info.Name)
+	}
+
+	if info.DisplayName != "Described Fake" || info.Input.MaxLength != 16 || !info.Input.Binary {
+		t.Fatalf("unexpected metadata: %#v", info)
+	}
+}
+
+func TestDescribe_withoutDescriber_FallsBackToName(t *testing.T) {
+	info := Describe(&Fake{})
+
+	if info.Name != "fake" || info.DisplayName != "fake" {
+		t.Fatalf("expected name only metadata, got %#v", info)
+	}
+}
+
+type PanickingDescriber struct{ Fake }
+
+func (fake *PanickingDescriber) Name() string       { return "panicking-describer" }
+func (fake *PanickingDescriber) Describe() AlgoInfo { panic("describe") }
+
+func TestDescribe_withPanickingDescriber_FallsBackToName(t *testing.T) {
+	info := Describe(&PanickingDescriber{})
+
+	if info.Name != "panicking-describer" || info.DisplayName != "panicking-describer" {
+		t.Fatalf("expected name only metadata, got %#v", info)
+	}
+}
+
+func TestAlgoInfos_IsSortedByName(t *testing.T) {
+	Register(func() AlgoPlugin { return &DescribedFake{} })
+	Register(func() AlgoPlugin { return &PanickingDescriber{} })
+
+	infos := AlgoInfos()
+	for i := 1; i < len(infos); i++ {
+		if infos[i-1].Name > infos[i].Name {
+			t.Fatalf("infos not sorted: %s before %s", infos[i-1].Name, infos[i].Name)
+		}
+	}
+}
+
+// Passer - a fake Positioner that makes two passes over its input, each
+// completed with a step past its end
+type Passer struct {
+	pos, passes, inputLen int
+}
+
+func (p *Passer) Name() string { return "passer" }
+func (p *Passer) Init(inputLen int) error {
+	p.pos, p.passes, p.inputLen = 0, 0, inputLen
+	return nil
+}
+func (p *Passer) Step(d byte) error {
+	if p.pos++; p.pos > p.inputLen {
+		p.pos, p.passes = 0, p.passes+1
+	}
+	return nil
+}
+func (p *Passer) SerializeState() (string, error) { return fmt.Sprintf("%d/%d", p.pos, p.passes), nil }
+func (p *Passer) DeserializeState(state string) error {
+	_, err := fmt.Sscanf(state, "%d/%d", &p.pos, &p.passes)
+	return err
+}
+func (p *Passer) Done() bool              { return p.passes == 2 }
+func (p *Passer) Output() (string, error) { return "", nil }
+func (p *Passer) Position() int           { return p.pos }
+
+// stepThrough steps algo with NextInput until it needs no more input
+func stepThrough(algo AlgoPlugin, input []byte) []byte {
+	var steps []byte
+	for d, ok := NextInput(algo, input, 0); ok; d, ok = NextInput(algo, input, len(steps)) {
+		algo.Step(d)
+		steps = append(steps, d)
+	}
+	return steps
+}
+
+func TestNextInput_withoutPositioner_StepsThroughTheInputOnce(t *testing.T) {
+	algo := &Summer{}
+	algo.Init(3)
+
+	if steps := stepThrough(algo, []byte("abc")); string(steps) != "abc" {
+		t.Fatalf("expected to step through the input once, got %q", steps)
+	}
+	if Cursor(algo, 3) != 3 {
+		t.Fatalf("expected the cursor to be the steps taken, got %d", Cursor(algo, 3))
+	}
+}
+
+func TestNextInput_withPositioner_StepsEveryPassUntilDone(t *testing.T) {
+	algo := &Passer{}
+	algo.Init(3)
+
+	if steps := stepThrough(algo, []byte("abc")); string(steps) != "abc\x00abc\x00" {
+		t.Fatalf("expected two passes each completed with a zero byte, got %q", steps)
+	}
+	if _, ok := NextInput(algo, []byte("abc"), 0); ok {
+		t.Fatal("expected no more input once done")
+	}
+}
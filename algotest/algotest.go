@@ -0,0 +1,373 @@
+// Package algotest checks that a registered algoexplore plugin keeps the
+// contract the server and the trace replayer rely on. A plugin opts in with
+// a single test:
+//
+//	func TestConformance(t *testing.T) {
+//		algotest.Run(t, "myalgo", algotest.Config{
+//			Input:  []byte("some input"),
+//			Golden: "testdata/myalgo.trace.json",
+//		})
+//	}
+//
+// The golden trace is recorded, or re-recorded after an intended change, with
+//
+//	go test -run TestConformance -algotest.update
+package algotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joekir/algoexplore"
+)
+
+var update = flag.Bool("algotest.update", false, "record the golden traces rather than checking them")
+
+// unknownField is added to a serialized state, which must then be rejected
+const unknownField = "algotest_unknown"
+
+// ErrNotJSONObject is returned by CheckUnknownFields for a plugin whose
+// serialized state is not a JSON object, so has no fields to reject
+var ErrNotJSONObject = errors.New("the serialized state is not a JSON object")
+
+// ErrNoSchema is returned by CheckSchema for a plugin that is not an
+// algoexplore.StateDescriber
+var ErrNoSchema = errors.New("the plugin does not describe its state")
+
+// Config - what a plugin is checked with
+type Config struct {
+	// Name of the subtest, the plugin's name if empty, to tell apart the
+	// configs a plugin is checked with
+	Name string
+	// Input the plugin is stepped over, it should be long enough to
+	// exercise every branch worth keeping
+	Input []byte
+	// Options the plugin is initialized with, if any
+	Options json.RawMessage
+	// Golden is the path of the golden trace, usually under testdata
+	Golden string
+}
+
+// Run runs every check against the registered plugin name as a subtest
+func Run(t *testing.T, name string, cfg Config) {
+	t.Helper()
+
+	test := cfg.Name
+	if len(test) == 0 {
+		test = name
+	}
+	t.Run(test, func(t *testing.T) {
+		t.Run("RoundTrip", func(t *testing.T) {
+			if err := CheckRoundTrip(name, cfg); err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("InitResets", func(t *testing.T) {
+			if err := CheckInitResets(name, cfg); err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("UnknownFields", func(t *testing.T) {
+			err := CheckUnknownFields(name, cfg)
+			if err == ErrNotJSONObject {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("Schema", func(t *testing.T) {
+			err := CheckSchema(name, cfg)
+			if err == ErrNoSchema {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("Golden", func(t *testing.T) {
+			if *update {
+				if err := UpdateGolden(name, cfg); err != nil {
+					t.Fatal(err)
+				}
+				t.Logf("recorded %s", cfg.Golden)
+				return
+			}
+			if err := CheckGolden(name, cfg); err != nil {
+				t.Fatal(err)
+			}
+		})
+	})
+}
+
+// CheckRoundTrip steps the plugin over the input and, at every index, checks
+// that a fresh instance given the serialized state then stepped with the same
+// byte ends up exactly where the plugin stepped directly does. This is how the
+// server steps a session, so any state the plugin fails to serialize shows up.
+func CheckRoundTrip(name string, cfg Config) error {
+	direct, err := newAlgo(name, cfg)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		d, ok := algoexplore.NextInput(direct, cfg.Input, i)
+		if !ok {
+			return nil
+		}
+		if i > maxSteps(cfg.Input) {
+			return fmt.Errorf("still stepping after %d steps over %d bytes", i, len(cfg.Input))
+		}
+
+		before, err := snapshotOf(direct)
+		if err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+
+		restored, err := algoexplore.GetAlgo(name)
+		if err != nil {
+			return err
+		}
+		if err := restored.DeserializeState(before.State); err != nil {
+			return fmt.Errorf("step %d: failed to deserialize its own state: %v", i, err)
+		}
+		after, err := snapshotOf(restored)
+		if err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+		if err := before.compare(after); err != nil {
+			return fmt.Errorf("step %d: deserializing then serializing changed the state: %v", i, err)
+		}
+
+		if err := direct.Step(d); err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+		if err := restored.Step(d); err != nil {
+			return fmt.Errorf("step %d: failed after deserializing: %v", i, err)
+		}
+
+		stepped, err := snapshotOf(direct)
+		if err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+		resumed, err := snapshotOf(restored)
+		if err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+		if err := stepped.compare(resumed); err != nil {
+			return fmt.Errorf("step %d: stepping a deserialized state differs from stepping directly: %v", i, err)
+		}
+	}
+}
+
+// CheckInitResets steps the plugin over the whole input, initializes it again
+// and checks nothing is left over from the first run
+func CheckInitResets(name string, cfg Config) error {
+	algo, err := newAlgo(name, cfg)
+	if err != nil {
+		return err
+	}
+	initial, err := snapshotOf(algo)
+	if err != nil {
+		return err
+	}
+
+	if _, err := stepAll(algo, cfg.Input); err != nil {
+		return err
+	}
+	if err := algoexplore.InitAlgo(algo, len(cfg.Input), cfg.Options); err != nil {
+		return err
+	}
+
+	reset, err := snapshotOf(algo)
+	if err != nil {
+		return err
+	}
+	if err := initial.compare(reset); err != nil {
+		return fmt.Errorf("init after a run differs from a fresh init: %v", err)
+	}
+	return nil
+}
+
+// CheckUnknownFields checks the plugin rejects a serialized state with a field
+// it does not know, both initially and after stepping over the input, rather
+// than silently dropping it. ErrNotJSONObject is returned, without checking,
+// if the state is not a JSON object.
+func CheckUnknownFields(name string, cfg Config) error {
+	algo, err := newAlgo(name, cfg)
+	if err != nil {
+		return err
+	}
+
+	initial, err := algo.SerializeState()
+	if err != nil {
+		return err
+	}
+	if _, err := stepAll(algo, cfg.Input); err != nil {
+		return err
+	}
+	final, err := algo.SerializeState()
+	if err != nil {
+		return err
+	}
+
+	for _, state := range []string{initial, final} {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(state), &fields); err != nil || fields == nil {
+			return ErrNotJSONObject
+		}
+		fields[unknownField] = json.RawMessage("1")
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+
+		fresh, err := algoexplore.GetAlgo(name)
+		if err != nil {
+			return err
+		}
+		if err := fresh.DeserializeState(string(data)); err == nil {
+			return fmt.Errorf("a state with the unknown field %q was accepted", unknownField)
+		}
+	}
+	return nil
+}
+
+// CheckSchema checks that every path in the plugin's state schema, including
+// the paths of highlighted rows, is in its serialized state, both initially and
+// after stepping over the input, and that no field is described twice.
+// ErrNoSchema is returned, without checking, if the plugin does not describe
+// its state.
+func CheckSchema(name string, cfg Config) error {
+	algo, err := newAlgo(name, cfg)
+	if err != nil {
+		return err
+	}
+	schema, ok := algoexplore.SchemaOf(algo)
+	if !ok {
+		return ErrNoSchema
+	}
+
+	described := make(map[string]bool, len(schema.Fields))
+	for _, field := range schema.Fields {
+		if described[field.Path] {
+			return fmt.Errorf("schema path %s is described twice, so would be drawn twice", field.Path)
+		}
+		described[field.Path] = true
+	}
+
+	initial, err := algo.SerializeState()
+	if err != nil {
+		return err
+	}
+	if _, err := stepAll(algo, cfg.Input); err != nil {
+		return err
+	}
+	final, err := algo.SerializeState()
+	if err != nil {
+		return err
+	}
+
+	for _, state := range []string{initial, final} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(state), &v); err != nil {
+			return fmt.Errorf("the state is described but is not JSON: %v", err)
+		}
+
+		for _, field := range schema.Fields {
+			for _, path := range []string{field.Path, field.Highlight} {
+				if len(path) > 0 && lookup(v, path) == nil {
+					return fmt.Errorf("schema path %s is not in the serialized state", path)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CheckGolden checks the plugin steps over the input as the golden trace
+// recorded, then replays the trace verifying every state
+func CheckGolden(name string, cfg Config) error {
+	data, err := ioutil.ReadFile(cfg.Golden)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no golden trace, record it with -algotest.update: %v", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	var trace algoexplore.Trace
+	var r io.Reader = bytes.NewReader(data)
+	if err := algoexplore.StrictUnmarshalJSON(&r, &trace); err != nil {
+		return fmt.Errorf("invalid golden trace %s: %v", cfg.Golden, err)
+	}
+	if !bytes.Equal(trace.Input, cfg.Input) {
+		return fmt.Errorf("the golden trace is of another input, re-record it with -algotest.update")
+	}
+	if len(trace.States) == 0 {
+		return fmt.Errorf("the golden trace has no states to check")
+	}
+
+	algo, err := newAlgo(name, cfg)
+	if err != nil {
+		return err
+	}
+	steps, err := stepAll(algo, cfg.Input)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(steps, trace.Steps) {
+		return fmt.Errorf("took %d steps over the input, the golden trace took %d", len(steps), len(trace.Steps))
+	}
+
+	algo, err = algoexplore.GetAlgo(name)
+	if err != nil {
+		return err
+	}
+	if err := trace.Replay(algo, nil); err != nil {
+		if m, ok := err.(*algoexplore.TraceMismatchError); ok {
+			return fmt.Errorf("%v\nrecorded: %s\nreplayed: %s", m, m.Recorded, m.Replayed)
+		}
+		return err
+	}
+	return nil
+}
+
+// UpdateGolden records the plugin stepping over the input, with every state,
+// as the golden trace
+func UpdateGolden(name string, cfg Config) error {
+	algo, err := newAlgo(name, cfg)
+	if err != nil {
+		return err
+	}
+	steps, err := stepAll(algo, cfg.Input)
+	if err != nil {
+		return err
+	}
+
+	algo, err = algoexplore.GetAlgo(name)
+	if err != nil {
+		return err
+	}
+	trace, err := algoexplore.RecordTrace(algo, len(cfg.Input), cfg.Input, cfg.Options, steps, true)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Golden), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cfg.Golden, append(data, '\n'), 0644)
+}
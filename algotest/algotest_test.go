@@ -0,0 +1,166 @@
+package algotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joekir/algoexplore"
+)
+
+// Counter sums its input, it is broken in the ways below when asked to be
+type Counter struct {
+	Sum   int  `json:"sum"`
+	Steps int  `json:"steps"`
+	Len   int  `json:"len"`
+	flaws flaw // how it is broken
+}
+
+type flaw int
+
+const (
+	sound       flaw = iota
+	forgetsLen       // Done depends on state it does not serialize
+	keepsSum         // Init does not reset the sum
+	lenientJSON      // unknown fields are silently dropped
+	badSchema        // the schema describes a field that is not in the state
+	twiceSchema      // the schema describes a field twice
+)
+
+func newCounter(name string, f flaw) algoexplore.AlgoFactory {
+	return func() algoexplore.AlgoPlugin { return &namedCounter{Counter{flaws: f}, name} }
+}
+
+type namedCounter struct {
+	Counter
+	name string
+}
+
+func (c *namedCounter) Name() string { return c.name }
+
+func (c *Counter) Init(inputLen int) error {
+	if c.flaws != keepsSum {
+		c.Sum = 0
+	}
+	c.Steps, c.Len = 0, inputLen
+	return nil
+}
+
+func (c *Counter) Step(d byte) error {
+	c.Sum += int(d)
+	c.Steps++
+	return nil
+}
+
+func (c *Counter) SerializeState() (string, error) {
+	state := *c
+	if c.flaws == forgetsLen {
+		state.Len = 0
+	}
+	data, err := json.Marshal(&state)
+	return string(data), err
+}
+
+func (c *Counter) DeserializeState(state string) error {
+	if c.flaws == lenientJSON {
+		return json.Unmarshal([]byte(state), c)
+	}
+	var r io.Reader = strings.NewReader(state)
+	return algoexplore.StrictUnmarshalJSON(&r, c)
+}
+
+func (c *Counter) StateSchema() algoexplore.StateSchema {
+	schema := algoexplore.StateSchema{Fields: []algoexplore.FieldSchema{
+		{Path: "sum", Label: "Sum", Kind: algoexplore.KindScalar},
+		{Path: "steps", Label: "Steps", Kind: algoexplore.KindScalar, Order: 1},
+	}}
+	switch c.flaws {
+	case badSchema:
+		schema.Fields = append(schema.Fields, algoexplore.FieldSchema{Path: "total.sum", Label: "Total"})
+	case twiceSchema:
+		schema.Fields = append(schema.Fields, algoexplore.FieldSchema{Path: "sum", Label: "Sum Again"})
+	}
+	return schema
+}
+
+func (c *Counter) Done() bool              { return c.Steps >= c.Len }
+func (c *Counter) Output() (string, error) { return "", nil }
+
+func init() {
+	algoexplore.Register(newCounter("counter", sound))
+	algoexplore.Register(newCounter("counter-forgets-len", forgetsLen))
+	algoexplore.Register(newCounter("counter-keeps-sum", keepsSum))
+	algoexplore.Register(newCounter("counter-lenient-json", lenientJSON))
+	algoexplore.Register(newCounter("counter-bad-schema", badSchema))
+	algoexplore.Register(newCounter("counter-twice-schema", twiceSchema))
+}
+
+var input = []byte{1, 2, 3}
+
+func TestRun_withSoundPlugin_Passes(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "counter.trace.json")
+	cfg := Config{Input: input, Golden: golden}
+	if err := UpdateGolden("counter", cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	Run(t, "counter", cfg)
+}
+
+func TestCheckRoundTrip_withUnserializedState_Fails(t *testing.T) {
+	err := CheckRoundTrip("counter-forgets-len", Config{Input: input})
+	if err == nil || !strings.Contains(err.Error(), "done") {
+		t.Fatalf("expected done to differ after deserializing, got %v", err)
+	}
+}
+
+func TestCheckInitResets_withLeftoverState_Fails(t *testing.T) {
+	if err := CheckInitResets("counter-keeps-sum", Config{Input: input}); err == nil {
+		t.Fatal("expected the sum left from the first run to be found")
+	}
+}
+
+func TestCheckUnknownFields_withLenientPlugin_Fails(t *testing.T) {
+	if err := CheckUnknownFields("counter-lenient-json", Config{Input: input}); err == nil {
+		t.Fatal("expected the unknown field to be found accepted")
+	}
+}
+
+func TestCheckSchema_withMissingPath_Fails(t *testing.T) {
+	err := CheckSchema("counter-bad-schema", Config{Input: input})
+	if err == nil || !strings.Contains(err.Error(), "total.sum") {
+		t.Fatalf("expected the missing schema path to be found, got %v", err)
+	}
+}
+
+func TestCheckSchema_withPathDescribedTwice_Fails(t *testing.T) {
+	if err := CheckSchema("counter-twice-schema", Config{Input: input}); err == nil {
+		t.Fatal("expected the field described twice to be found")
+	}
+}
+
+func TestCheckGolden_withChangedPlugin_Fails(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "counter.trace.json")
+	if err := UpdateGolden("counter", Config{Input: input, Golden: golden}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same trace recorded for another plugin
+	if err := CheckGolden("counter-keeps-sum", Config{Input: input, Golden: golden}); err == nil {
+		t.Fatal("expected the trace of another plugin to be rejected")
+	}
+
+	if err := CheckGolden("counter", Config{Input: bytes.Repeat(input, 2), Golden: golden}); err == nil {
+		t.Fatal("expected the trace of another input to be rejected")
+	}
+}
+
+func TestCheckGolden_withoutGolden_Fails(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "missing.trace.json")
+	if err := CheckGolden("counter", Config{Input: input, Golden: golden}); err == nil {
+		t.Fatal("expected a missing golden trace to fail")
+	}
+}
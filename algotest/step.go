@@ -0,0 +1,92 @@
+package algotest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joekir/algoexplore"
+)
+
+// snapshot - everything observable about a plugin between steps
+type snapshot struct {
+	State     string
+	Done      bool
+	Output    string
+	OutputErr string
+}
+
+func snapshotOf(algo algoexplore.AlgoPlugin) (snapshot, error) {
+	state, err := algo.SerializeState()
+	if err != nil {
+		return snapshot{}, fmt.Errorf("failed to serialize state: %v", err)
+	}
+
+	s := snapshot{State: state, Done: algo.Done()}
+	if s.Output, err = algo.Output(); err != nil {
+		s.OutputErr = err.Error()
+	}
+	return s, nil
+}
+
+// compare returns an error describing the first difference from o
+func (s snapshot) compare(o snapshot) error {
+	switch {
+	case s.State != o.State:
+		return fmt.Errorf("state\nwant: %s\n got: %s", s.State, o.State)
+	case s.Done != o.Done:
+		return fmt.Errorf("done: want %v, got %v", s.Done, o.Done)
+	case s.Output != o.Output || s.OutputErr != o.OutputErr:
+		return fmt.Errorf("output: want %q (%s), got %q (%s)", s.Output, s.OutputErr, o.Output, o.OutputErr)
+	}
+	return nil
+}
+
+// newAlgo returns the registered plugin name initialized for the input
+func newAlgo(name string, cfg Config) (algoexplore.AlgoPlugin, error) {
+	algo, err := algoexplore.GetAlgo(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := algoexplore.InitAlgo(algo, len(cfg.Input), cfg.Options); err != nil {
+		return nil, fmt.Errorf("failed to init: %v", err)
+	}
+	return algo, nil
+}
+
+// maxSteps bounds the steps over input, so that a plugin which never finishes
+// fails rather than hangs
+func maxSteps(input []byte) int {
+	return 64 * (len(input) + 1)
+}
+
+// stepAll steps the plugin until it needs no more steps, returning the bytes
+// it was stepped with
+func stepAll(algo algoexplore.AlgoPlugin, input []byte) ([]byte, error) {
+	var steps []byte
+	for {
+		d, ok := algoexplore.NextInput(algo, input, len(steps))
+		if !ok {
+			return steps, nil
+		}
+		if len(steps) > maxSteps(input) {
+			return nil, fmt.Errorf("still stepping after %d steps over %d bytes", len(steps), len(input))
+		}
+		if err := algo.Step(d); err != nil {
+			return nil, fmt.Errorf("step %d: %v", len(steps), err)
+		}
+		steps = append(steps, d)
+	}
+}
+
+// lookup returns the value at the dotted path in a decoded JSON state, nil if
+// there is none
+func lookup(state interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := state.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		state = object[key]
+	}
+	return state
+}
@@ -0,0 +1,189 @@
+// Command algoexplore steps any registered algorithm over a file or stdin in
+// the terminal, for scripting through an algorithm without the web server.
+//
+//	algoexplore list
+//	algoexplore run [-mode jsonl|triggers|output|tty] [-options JSON] <algo> [file]
+//	algoexplore ssdeep [-r] [-m known.txt | -p] [-t threshold] <paths...>
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joekir/algoexplore"
+	_ "github.com/joekir/algoexplore/internal/algos/ctph"
+)
+
+const noColorEnvVarName = "NO_COLOR"
+
+// errUsage is returned for a malformed command line, after the usage is printed
+var errUsage = errors.New("usage")
+
+func main() {
+	err := command(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	switch {
+	case err == errUsage:
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "algoexplore: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// command runs the subcommand named by the first argument
+func command(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) < 1 {
+		usage(stderr)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return list(stdout)
+	case "run":
+		return runCommand(args[1:], stdin, stdout, stderr)
+	case "ssdeep":
+		return ssdeepCommand(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return nil
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		usage(stderr)
+		return errUsage
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, `usage:
+  algoexplore list
+        lists the registered algorithms
+  algoexplore run [flags] <algo> [file]
+        steps the algorithm over the file, or stdin if there is none or it is -`)
+	runFlags(w, &runConfig{}).PrintDefaults()
+	fmt.Fprintln(w, `  algoexplore ssdeep [flags] <paths...>
+        prints the ssdeep hashes of the files, or their matches, as ssdeep does`)
+	ssdeepFlags(w, &ssdeepConfig{}).PrintDefaults()
+}
+
+// list prints the name and description of every registered algorithm
+func list(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, info := range algoexplore.AlgoInfos() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", info.Name, info.DisplayName, info.Description)
+	}
+	return tw.Flush()
+}
+
+type runConfig struct {
+	mode    string
+	options string
+	noColor bool
+}
+
+func runFlags(w io.Writer, config *runConfig) *flag.FlagSet {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.StringVar(&config.mode, "mode", "",
+		"what to print: jsonl (every state), triggers (states where a trigger fired), output (the final output) "+
+			"or tty (step interactively), defaults to tty on a terminal and jsonl otherwise")
+	fs.StringVar(&config.options, "options", "", "JSON object of the algorithm's options, see its OptionsSchema")
+	fs.BoolVar(&config.noColor, "no_color", os.Getenv(noColorEnvVarName) != "", "don't color the tty mode")
+	return fs
+}
+
+// runCommand steps the algorithm named on the command line over its input
+func runCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var config runConfig
+	fs := runFlags(stderr, &config)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return errUsage
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		usage(stderr)
+		return errUsage
+	}
+
+	mode := config.mode
+	if len(mode) < 1 {
+		mode = modeJSONL
+		if isTerminal(stdout) {
+			mode = modeTTY
+		}
+	}
+	switch mode {
+	case modeJSONL, modeTriggers, modeOutput, modeTTY:
+	default:
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+
+	algo, err := algoexplore.GetAlgo(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	input, fromStdin, err := readInput(fs.Arg(1), stdin)
+	if err != nil {
+		return err
+	}
+
+	var options json.RawMessage
+	if len(config.options) > 0 {
+		options = json.RawMessage(config.options)
+	}
+
+	s, err := newStepper(algo, input, options)
+	if err != nil {
+		return err
+	}
+
+	if mode != modeTTY {
+		return s.print(stdout, mode)
+	}
+
+	// the keys come from the terminal when stdin is the input
+	keys := stdin
+	if fromStdin {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			return fmt.Errorf("stepping interactively needs a terminal: %v", err)
+		}
+		defer tty.Close()
+		keys = tty
+	}
+	return s.interact(keys, stdout, !config.noColor && isTerminal(stdout))
+}
+
+// readInput reads the whole of the file at path, or stdin if path is empty or -
+func readInput(path string, stdin io.Reader) (input []byte, fromStdin bool, err error) {
+	if len(path) < 1 || path == "-" {
+		input, err = ioutil.ReadAll(stdin)
+		return input, true, err
+	}
+
+	input, err = ioutil.ReadFile(path)
+	return input, false, err
+}
+
+// isTerminal reports whether w is a character device, e.g. a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/joekir/algoexplore"
+	"github.com/joekir/algoexplore/internal/algos/ctph"
+)
+
+const crowAndTheFox = "../../internal/algos/ctph/testdata/crowandthefox.txt"
+
+// runCLI runs the command line with the input on stdin, returning its stdout
+func runCLI(t *testing.T, input string, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	err := command(args, strings.NewReader(input), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+// ctphHash returns the ssdeep signature of data, failing the test if it cannot
+func ctphHash(t *testing.T, data []byte) string {
+	t.Helper()
+
+	h, err := ctph.Hash(data)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	return h
+}
+
+// readRecords decodes every line of jsonl output
+func readRecords(t *testing.T, out string) []record {
+	t.Helper()
+
+	var records []record
+	lines := bufio.NewScanner(strings.NewReader(out))
+	for lines.Scan() {
+		var r record
+		if err := json.Unmarshal(lines.Bytes(), &r); err != nil {
+			t.Fatalf("line is not a record: %v: %s", err, lines.Text())
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestList_PrintsEveryAlgo(t *testing.T) {
+	out, err := runCLI(t, "", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range algoexplore.Algos() {
+		if !strings.Contains(out, name+" ") {
+			t.Fatalf("expected %s to be listed, got:\n%s", name, out)
+		}
+	}
+}
+
+func TestRun_withOutputMode_PrintsTheSsdeepHash(t *testing.T) {
+	input, err := ioutil.ReadFile(crowAndTheFox)
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+
+	for _, name := range []string{"ctph", "ctph-stream"} {
+		out, err := runCLI(t, "", "run", "-mode", "output", name, crowAndTheFox)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if expected := ctphHash(t, input) + "\n"; out != expected {
+			t.Fatalf("%s printed the wrong output: got %q want %q", name, out, expected)
+		}
+	}
+}
+
+func TestRun_withJSONLMode_PrintsEveryStateFromStdin(t *testing.T) {
+	text := "The quick brown fox jumped over the lazy dog's back"
+	out, err := runCLI(t, text, "run", "rollinghash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := readRecords(t, out)
+	if len(records) != len(text) {
+		t.Fatalf("expected a state per byte: got %d want %d", len(records), len(text))
+	}
+
+	for i, r := range records {
+		if r.Step != i+1 || r.Index != i || r.Byte != text[i] || r.Done != (i == len(text)-1) {
+			t.Fatalf("unexpected record %d: %+v", i, r)
+		}
+
+		var state ctph.RollingHashAlgo
+		if err := json.Unmarshal(r.State, &state); err != nil {
+			t.Fatalf("state is not the algorithm's: %v", err)
+		}
+	}
+}
+
+func TestRun_withTriggersMode_PrintsOnlyStatesThatFired(t *testing.T) {
+	all, err := runCLI(t, "", "run", "-mode", "jsonl", "ctph", crowAndTheFox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := runCLI(t, "", "run", "-mode", "triggers", "ctph", crowAndTheFox)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected []record
+	for _, r := range readRecords(t, all) {
+		if len(r.Triggers) > 0 {
+			expected = append(expected, r)
+		}
+	}
+
+	triggered := readRecords(t, out)
+	if len(triggered) < 1 || len(triggered) != len(expected) {
+		t.Fatalf("expected only the states that fired: got %d want %d", len(triggered), len(expected))
+	}
+	for i, r := range triggered {
+		if r.Step != expected[i].Step || strings.Join(r.Triggers, ",") != strings.Join(expected[i].Triggers, ",") {
+			t.Fatalf("unexpected record %d: got %+v want %+v", i, r, expected[i])
+		}
+	}
+}
+
+func TestRun_withTriggersMode_RejectsAlgosWithoutTriggers(t *testing.T) {
+	if _, err := runCLI(t, "abc", "run", "-mode", "triggers", "fnv32-ssdeep"); err == nil {
+		t.Fatal("expected an error for an algo without trigger fields")
+	}
+}
+
+func TestRun_withOptions_InitializesTheAlgoWithThem(t *testing.T) {
+	out, err := runCLI(t, "", "run", "-mode", "output", "-options", `{"block_size_min": 6}`, "ctph", crowAndTheFox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// block sizes double from the minimum
+	bs, err := strconv.Atoi(strings.SplitN(out, ":", 2)[0])
+	if err != nil || bs%6 != 0 {
+		t.Fatalf("expected a block size from the minimum of 6, got %q", out)
+	}
+
+	if _, err := runCLI(t, "", "run", "-options", `{"nope": 1}`, "ctph", crowAndTheFox); err == nil {
+		t.Fatal("expected an error for an unknown option")
+	}
+}
+
+func TestRun_withBadArguments_ReturnsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no command", nil},
+		{"unknown command", []string{"walk"}},
+		{"no algo", []string{"run"}},
+		{"too many files", []string{"run", "ctph", "a", "b"}},
+		{"unknown algo", []string{"run", "nope", crowAndTheFox}},
+		{"unknown mode", []string{"run", "-mode", "nope", "ctph", crowAndTheFox}},
+		{"missing file", []string{"run", "ctph", "testdata/nope.txt"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := runCLI(t, "", tt.args...); err == nil {
+			t.Fatalf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestRun_withTTYMode_StepsAsTyped(t *testing.T) {
+	input, err := ioutil.ReadFile(crowAndTheFox)
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+
+	// the keys are read from stdin as the input is a file
+	out, err := runCLI(t, "\n2\nx\nc\n", "run", "-mode", "tty", "ctph", crowAndTheFox)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"ctph  step 0", "ctph  step 1  input 0/", "ctph  step 3  input 2/",
+		`not a number of steps: "x"`, "Output", ctphHash(t, input), "done"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %q in the view, got:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Fatal("expected no colour when not writing to a terminal")
+	}
+}
+
+func TestRun_withTTYMode_QuitsWhenAsked(t *testing.T) {
+	out, err := runCLI(t, "q\n", "run", "-mode", "tty", "ctph", crowAndTheFox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "step 1") || strings.Contains(out, "done") {
+		t.Fatalf("expected to quit before stepping, got:\n%s", out)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/joekir/algoexplore"
+)
+
+const (
+	modeJSONL    = "jsonl"
+	modeTriggers = "triggers"
+	modeOutput   = "output"
+	modeTTY      = "tty"
+)
+
+// stepper drives an algorithm over its whole input, in the order the
+// algorithm asks for it
+type stepper struct {
+	algo   algoexplore.AlgoPlugin
+	input  []byte
+	schema algoexplore.StateSchema
+	steps  int
+}
+
+// record - one line of the jsonl and triggers modes, the state after a step
+type record struct {
+	Step     int             `json:"step"`
+	Index    int             `json:"index"`
+	Byte     byte            `json:"byte"`
+	Triggers []string        `json:"triggers,omitempty"`
+	Done     bool            `json:"done"`
+	Output   string          `json:"output"`
+	State    json.RawMessage `json:"state"`
+}
+
+// newStepper initializes the algorithm for the input, with options if it takes any
+func newStepper(algo algoexplore.AlgoPlugin, input []byte, options json.RawMessage) (*stepper, error) {
+	if err := algoexplore.InitAlgo(algo, len(input), options); err != nil {
+		return nil, fmt.Errorf("failed to initialize %s: %v", algo.Name(), err)
+	}
+
+	schema, _ := algoexplore.SchemaOf(algo)
+	return &stepper{algo: algo, input: input, schema: schema}, nil
+}
+
+// cursor returns the index of the input byte the algorithm consumes next
+func (s *stepper) cursor() int {
+	return algoexplore.Cursor(s.algo, s.steps)
+}
+
+// next returns the input byte to step next, ok is false once the algorithm
+// is done or needs no more input
+func (s *stepper) next() (d byte, ok bool) {
+	return algoexplore.NextInput(s.algo, s.input, s.steps)
+}
+
+// step steps the next input byte, ok is false if there was none to step
+func (s *stepper) step() (r record, ok bool, err error) {
+	d, ok := s.next()
+	if !ok {
+		return record{}, false, nil
+	}
+
+	index := s.cursor()
+	if err := s.algo.Step(d); err != nil {
+		return record{}, false, fmt.Errorf("step %d failed: %v", s.steps+1, err)
+	}
+	s.steps++
+
+	r, err = s.record()
+	r.Index, r.Byte = index, d
+	return r, true, err
+}
+
+// record captures the algorithm's current state
+func (s *stepper) record() (record, error) {
+	state, err := s.algo.SerializeState()
+	if err != nil {
+		return record{}, err
+	}
+	output, err := s.algo.Output()
+	if err != nil {
+		return record{}, err
+	}
+
+	r := record{Step: s.steps, Done: s.algo.Done(), Output: output}
+	if json.Valid([]byte(state)) {
+		r.State = json.RawMessage(state)
+		r.Triggers = s.triggered(decodeState(state))
+	} else {
+		// keep a state that isn't JSON as a JSON string
+		if r.State, err = json.Marshal(state); err != nil {
+			return record{}, err
+		}
+	}
+	return r, nil
+}
+
+// triggered returns the labels of the trigger fields set in the state
+func (s *stepper) triggered(state interface{}) []string {
+	var labels []string
+	for _, field := range s.schema.Fields {
+		if field.Kind == algoexplore.KindTrigger && truthy(lookup(state, field.Path)) {
+			labels = append(labels, field.Label)
+		}
+	}
+	return labels
+}
+
+// hasTriggers reports whether the algorithm describes any trigger fields
+func (s *stepper) hasTriggers() bool {
+	for _, field := range s.schema.Fields {
+		if field.Kind == algoexplore.KindTrigger {
+			return true
+		}
+	}
+	return false
+}
+
+// print steps the algorithm over all of its input, printing what the mode asks for
+func (s *stepper) print(w io.Writer, mode string) error {
+	switch mode {
+	case modeJSONL, modeOutput:
+	case modeTriggers:
+		if !s.hasTriggers() {
+			return fmt.Errorf("%s does not describe any trigger fields", s.algo.Name())
+		}
+	default:
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+
+	enc := json.NewEncoder(w)
+	for {
+		r, ok, err := s.step()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		if mode == modeJSONL || (mode == modeTriggers && len(r.Triggers) > 0) {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	if mode != modeOutput {
+		return nil
+	}
+
+	output, err := s.algo.Output()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, output)
+	return err
+}
+
+// decodeState decodes a JSON state keeping its numbers exact
+func decodeState(state string) interface{} {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader([]byte(state)))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil
+	}
+	return v
+}
+
+// lookup returns the value at the dotted path of the state, nil if there is none
+func lookup(state interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		m, ok := state.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		state = m[key]
+	}
+	return state
+}
+
+// truthy reports whether a flag in the state is set, as the front end would
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f != 0
+	case string:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
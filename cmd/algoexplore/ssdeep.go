@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/joekir/algoexplore/ssdeep"
+)
+
+// ssdeepHeader is the first line of ssdeep's hash output and known hashes files
+const ssdeepHeader = "ssdeep,1.1--blocksize:hash:hash,filename"
+
+type ssdeepConfig struct {
+	recursive bool
+	known     string
+	pretty    bool
+	threshold int
+	workers   int
+}
+
+// fileHash - the ssdeep signature of a file, err is set if it couldn't be hashed
+type fileHash struct {
+	path string
+	hash string
+	err  error
+}
+
+func ssdeepFlags(w io.Writer, config *ssdeepConfig) *flag.FlagSet {
+	fs := flag.NewFlagSet("ssdeep", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.BoolVar(&config.recursive, "r", false, "hash the files in directories, recursively")
+	fs.StringVar(&config.known, "m", "", "match the files against the known hashes in this file, as written by ssdeep")
+	fs.BoolVar(&config.pretty, "p", false, "match every file against every other file")
+	fs.IntVar(&config.threshold, "t", 0, "only print matches that score above this threshold, from 0 to 100")
+	fs.IntVar(&config.workers, "j", runtime.NumCPU(), "how many files to hash at once")
+	return fs
+}
+
+// ssdeepCommand hashes the files on the command line as ssdeep does, printing
+// their hashes, or their matches with -m or -p
+func ssdeepCommand(args []string, stdout, stderr io.Writer) error {
+	var config ssdeepConfig
+	fs := ssdeepFlags(stderr, &config)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return errUsage
+	}
+
+	switch {
+	case fs.NArg() < 1:
+		usage(stderr)
+		return errUsage
+	case config.pretty && len(config.known) > 0:
+		return fmt.Errorf("-m and -p can't be used together")
+	case config.threshold < 0 || config.threshold > 100:
+		return fmt.Errorf("the threshold must be between 0 and 100")
+	case config.workers < 1:
+		return fmt.Errorf("at least 1 worker is needed")
+	}
+
+	var known []fileHash
+	if len(config.known) > 0 {
+		var err error
+		if known, err = readKnownHashes(config.known); err != nil {
+			return err
+		}
+	}
+
+	paths, failed := listFiles(fs.Args(), config.recursive, stderr)
+	hashes := hashFiles(paths, config.workers)
+
+	var ok []fileHash
+	for _, h := range hashes {
+		if h.err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", h.path, h.err)
+			failed++
+			continue
+		}
+		ok = append(ok, h)
+	}
+
+	var err error
+	switch {
+	case len(config.known) > 0:
+		err = printMatches(stdout, ok, known, config.threshold, config.known+":")
+	case config.pretty:
+		err = printPrettyMatches(stdout, ok, config.threshold)
+	default:
+		err = printHashes(stdout, ok)
+	}
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of the paths could not be hashed", failed)
+	}
+	return nil
+}
+
+// listFiles lists the regular files among paths, and those in its directories
+// when recursive. A path that can't be listed is reported and counted as failed.
+func listFiles(paths []string, recursive bool, stderr io.Writer) (files []string, failed int) {
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		switch {
+		case err != nil:
+			fmt.Fprintf(stderr, "%v\n", err)
+			failed++
+			continue
+		case !info.IsDir():
+			files = append(files, root)
+			continue
+		case !recursive:
+			fmt.Fprintf(stderr, "%s: is a directory\n", root)
+			failed++
+			continue
+		}
+
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				fmt.Fprintf(stderr, "%v\n", err)
+				failed++
+				return nil
+			}
+			if info.Mode().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			failed++
+		}
+	}
+	return files, failed
+}
+
+// hashFiles hashes the files on a pool of workers, the hashes are in the
+// order of the paths
+func hashFiles(paths []string, workers int) []fileHash {
+	hashes := make([]fileHash, len(paths))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				h, err := ssdeep.HashFile(paths[i])
+				hashes[i] = fileHash{path: paths[i], hash: h, err: err}
+			}
+		}()
+	}
+
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return hashes
+}
+
+// printHashes prints the hashes in ssdeep's format, which it can read back with
+// -m. Like ssdeep, there is no header without any hashes
+func printHashes(w io.Writer, hashes []fileHash) error {
+	if len(hashes) < 1 {
+		return nil
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, ssdeepHeader)
+	for _, h := range hashes {
+		fmt.Fprintf(bw, "%s,%s\n", h.hash, quoteFilename(h.path))
+	}
+	return bw.Flush()
+}
+
+// printMatches prints the known files each file matches above the threshold,
+// known files are named with the prefix
+func printMatches(w io.Writer, hashes, known []fileHash, threshold int, prefix string) error {
+	bw := bufio.NewWriter(w)
+	for _, h := range hashes {
+		for _, k := range known {
+			score, err := ssdeep.Compare(h.hash, k.hash)
+			if err != nil {
+				return fmt.Errorf("%s: %v", k.path, err)
+			}
+			if score > threshold {
+				fmt.Fprintf(bw, "%s matches %s%s (%d)\n", h.path, prefix, k.path, score)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// printPrettyMatches prints every file each file matches above the threshold,
+// with a blank line after the matches of each file
+func printPrettyMatches(w io.Writer, hashes []fileHash, threshold int) error {
+	bw := bufio.NewWriter(w)
+	for i, h := range hashes {
+		matched := false
+		for j, other := range hashes {
+			if i == j {
+				continue
+			}
+
+			score, err := ssdeep.Compare(h.hash, other.hash)
+			if err != nil {
+				return fmt.Errorf("%s: %v", h.path, err)
+			}
+			if score > threshold {
+				fmt.Fprintf(bw, "%s matches %s (%d)\n", h.path, other.path, score)
+				matched = true
+			}
+		}
+		if matched {
+			fmt.Fprintln(bw)
+		}
+	}
+	return bw.Flush()
+}
+
+// readKnownHashes reads a file of hashes written by ssdeep, or printHashes
+func readKnownHashes(path string) ([]fileHash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := bufio.NewScanner(f)
+	if !lines.Scan() || strings.TrimSpace(lines.Text()) != ssdeepHeader {
+		if err := lines.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: not an ssdeep hashes file, the first line must be %s", path, ssdeepHeader)
+	}
+
+	var known []fileHash
+	for n := 2; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if len(line) < 1 {
+			continue
+		}
+
+		parts := strings.SplitN(line, ",", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a hash and a filename", path, n)
+		}
+
+		// Compare rejects a malformed signature, even against itself
+		if _, err := ssdeep.Compare(parts[0], parts[0]); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		known = append(known, fileHash{path: unquoteFilename(parts[1]), hash: parts[0]})
+	}
+	return known, lines.Err()
+}
+
+// quoteFilename quotes a filename as ssdeep does, escaping any quotes in it
+func quoteFilename(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `\"`) + `"`
+}
+
+// unquoteFilename reverses quoteFilename, a filename without quotes is kept as is
+func unquoteFilename(name string) string {
+	if len(name) < 2 || !strings.HasPrefix(name, `"`) || !strings.HasSuffix(name, `"`) {
+		return name
+	}
+	return strings.ReplaceAll(name[1:len(name)-1], `\"`, `"`)
+}
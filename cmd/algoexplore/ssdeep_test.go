@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/joekir/algoexplore/internal/algos/ctph"
+)
+
+// writeTree writes the crow and the fox, a tweaked copy of it in a sub
+// directory and moby dick into a temporary directory
+func writeTree(t *testing.T) (dir string, files map[string][]byte) {
+	t.Helper()
+
+	original, err := ioutil.ReadFile(crowAndTheFox)
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+	other, err := ioutil.ReadFile("../../internal/algos/ctph/testdata/mobydick.txt")
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+
+	dir = t.TempDir()
+	files = map[string][]byte{
+		filepath.Join(dir, "crow.txt"):           original,
+		filepath.Join(dir, "sub", "tweaked.txt"): bytes.Replace(original, []byte("vous"), []byte("tous"), 1),
+		filepath.Join(dir, "moby.txt"):           other,
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, data := range files {
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, files
+}
+
+func TestSsdeep_withRecursion_PrintsTheHashOfEveryFile(t *testing.T) {
+	dir, files := writeTree(t)
+
+	out, err := runCLI(t, "", "ssdeep", "-r", "-j", "2", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != len(files)+1 || lines[0] != ssdeepHeader {
+		t.Fatalf("expected the header and a line per file, got:\n%s", out)
+	}
+	for path, data := range files {
+		expected := ctphHash(t, data) + `,"` + path + `"`
+		if !strings.Contains(out, expected+"\n") {
+			t.Fatalf("expected %s in the output, got:\n%s", expected, out)
+		}
+	}
+}
+
+func TestSsdeep_withoutRecursion_SkipsDirectories(t *testing.T) {
+	dir, _ := writeTree(t)
+	crow := filepath.Join(dir, "crow.txt")
+
+	out, err := runCLI(t, "", "ssdeep", crow, filepath.Join(dir, "sub"))
+	if err == nil {
+		t.Fatal("expected an error for the directory")
+	}
+	if !strings.Contains(out, crow) || strings.Contains(out, "tweaked.txt") {
+		t.Fatalf("expected only the file to be hashed, got:\n%s", out)
+	}
+}
+
+func TestSsdeep_withKnownHashes_PrintsMatchesAboveTheThreshold(t *testing.T) {
+	dir, files := writeTree(t)
+	crow, tweaked := filepath.Join(dir, "crow.txt"), filepath.Join(dir, "sub", "tweaked.txt")
+
+	known, err := runCLI(t, "", "ssdeep", crow, filepath.Join(dir, "moby.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	knownPath := filepath.Join(dir, "known.txt")
+	if err := ioutil.WriteFile(knownPath, []byte(known), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	score, err := ctph.Score(ctphHash(t, files[crow]), ctphHash(t, files[tweaked]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCLI(t, "", "ssdeep", "-m", knownPath, tweaked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := tweaked + " matches " + knownPath + ":" + crow + " (" + strconv.Itoa(score) + ")\n"
+	if out != expected {
+		t.Fatalf("unexpected matches: got %q want %q", out, expected)
+	}
+
+	out, err = runCLI(t, "", "ssdeep", "-m", knownPath, "-t", strconv.Itoa(score), tweaked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) > 0 {
+		t.Fatalf("expected no matches above the threshold, got %q", out)
+	}
+}
+
+func TestSsdeep_withPrettyMode_MatchesWithinTheSet(t *testing.T) {
+	dir, _ := writeTree(t)
+	crow, tweaked := filepath.Join(dir, "crow.txt"), filepath.Join(dir, "sub", "tweaked.txt")
+
+	out, err := runCLI(t, "", "ssdeep", "-r", "-p", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{crow + " matches " + tweaked + " (", tweaked + " matches " + crow + " ("} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected %q in the output, got:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "moby.txt") {
+		t.Fatalf("expected moby dick not to match, got:\n%s", out)
+	}
+}
+
+func TestSsdeep_withBadKnownHashes_ReturnsErrors(t *testing.T) {
+	dir, _ := writeTree(t)
+	crow := filepath.Join(dir, "crow.txt")
+
+	tests := map[string]string{
+		"no header":      "3:abc:def,\"a\"\n",
+		"no filename":    ssdeepHeader + "\n3:abc:def\n",
+		"malformed hash": ssdeepHeader + "\nnope,\"a\"\n",
+	}
+	for name, contents := range tests {
+		knownPath := filepath.Join(dir, "known.txt")
+		if err := ioutil.WriteFile(knownPath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := runCLI(t, "", "ssdeep", "-m", knownPath, crow); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestQuoteFilename_RoundTrips(t *testing.T) {
+	for _, name := range []string{"plain.txt", `say "hi".txt`, "with,comma.txt"} {
+		if got := unquoteFilename(quoteFilename(name)); got != name {
+			t.Fatalf("filename did not round trip: got %q want %q", got, name)
+		}
+	}
+}
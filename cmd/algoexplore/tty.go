@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joekir/algoexplore"
+)
+
+// ANSI escapes of the tty view
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+const ttyHelp = "[enter] step, <n> steps, c to continue to the end, q to quit"
+
+// ttyView draws the state after each step as a table of its fields, in
+// schema order. Fields that changed in the step are yellow, triggers that
+// fired are red and the output is green.
+type ttyView struct {
+	w        io.Writer
+	color    bool
+	previous map[string]string
+}
+
+// interact draws the initial state then steps as many bytes as each line read
+// from keys asks for, until the algorithm is done or the input runs out
+func (s *stepper) interact(keys io.Reader, w io.Writer, color bool) error {
+	v := &ttyView{w: w, color: color, previous: map[string]string{}}
+
+	r, err := s.record()
+	if err != nil {
+		return err
+	}
+	v.draw(s, r, -1)
+
+	lines := bufio.NewScanner(keys)
+	for {
+		if _, ok := s.next(); !ok {
+			fmt.Fprintln(w, v.paint(ansiGreen, "done"))
+			return nil
+		}
+
+		fmt.Fprintf(w, "%s> ", ttyHelp)
+		if !lines.Scan() {
+			fmt.Fprintln(w)
+			return lines.Err()
+		}
+
+		count, quit, err := parseKeys(lines.Text())
+		if err != nil {
+			fmt.Fprintln(w, v.paint(ansiRed, err.Error()))
+			continue
+		}
+		if quit {
+			fmt.Fprintln(w)
+			return nil
+		}
+
+		for i := 0; count < 0 || i < count; i++ {
+			next, ok, err := s.step()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+			r = next
+		}
+		v.draw(s, r, r.Index)
+	}
+}
+
+// parseKeys parses a line typed while stepping, a count of -1 steps to the end
+func parseKeys(line string) (count int, quit bool, err error) {
+	switch line = strings.TrimSpace(line); line {
+	case "":
+		return 1, false, nil
+	case "c":
+		return -1, false, nil
+	case "q":
+		return 0, true, nil
+	}
+
+	count, err = strconv.Atoi(line)
+	if err != nil || count < 1 {
+		return 0, false, fmt.Errorf("not a number of steps: %q", line)
+	}
+	return count, false, nil
+}
+
+// draw prints the record's state, index is the input byte just stepped, if any
+func (v *ttyView) draw(s *stepper, r record, index int) {
+	header := fmt.Sprintf("%s  step %d", s.algo.Name(), r.Step)
+	if index >= 0 {
+		header += fmt.Sprintf("  input %d/%d  byte 0x%02x %s", index, len(s.input), r.Byte, printable(r.Byte))
+	}
+	fmt.Fprintln(v.w, v.paint(ansiBold, header))
+
+	tw := tabwriter.NewWriter(v.w, 0, 4, 2, ' ', 0)
+	state := decodeState(string(r.State))
+	for _, field := range s.schema.Fields {
+		value := lookup(state, field.Path)
+		if field.Kind == algoexplore.KindTable {
+			v.drawTable(tw, field, value, lookup(state, field.Highlight))
+			continue
+		}
+
+		text := formatValue(field, value)
+		colour := ""
+		switch {
+		case field.Kind == algoexplore.KindTrigger && truthy(value):
+			colour = ansiRed
+		case field.Kind == algoexplore.KindOutput:
+			colour = ansiGreen
+		case v.previous[field.Path] != text && r.Step > 0:
+			colour = ansiYellow
+		}
+		v.previous[field.Path] = text
+
+		fmt.Fprintf(tw, "%s\t%s\n", v.paint(ansiCyan, field.Label), v.paint(colour, text))
+	}
+	if len(s.schema.Fields) < 1 {
+		fmt.Fprintf(tw, "%s\t%s\n", v.paint(ansiCyan, "State"), string(r.State))
+	}
+	fmt.Fprintf(tw, "%s\t%s\n", v.paint(ansiCyan, "Output"), v.paint(ansiGreen, r.Output))
+	tw.Flush()
+	fmt.Fprintln(v.w)
+}
+
+// drawTable prints a row per element of a table field, marking the highlighted row
+func (v *ttyView) drawTable(tw io.Writer, field algoexplore.FieldSchema, value, highlight interface{}) {
+	rows, _ := value.([]interface{})
+	fmt.Fprintf(tw, "%s\t%s\n", v.paint(ansiCyan, field.Label), "  "+strings.Join(field.Columns, "  "))
+
+	for i, row := range rows {
+		cells := make([]string, len(field.Columns))
+		for j, column := range field.Columns {
+			cells[j] = fmt.Sprint(lookup(row, column))
+		}
+
+		text := strings.Join(cells, "  ")
+		if n, ok := highlight.(json.Number); ok && n.String() == strconv.Itoa(i) {
+			text = v.paint(ansiYellow, "> "+text)
+		} else {
+			text = "  " + text
+		}
+		fmt.Fprintf(tw, "\t%s\n", text)
+	}
+}
+
+// paint colours the text, if the view is coloured
+func (v *ttyView) paint(colour, text string) string {
+	if !v.color || len(colour) < 1 {
+		return text
+	}
+	return colour + text + ansiReset
+}
+
+// formatValue renders a field's value as the front end would draw it
+func formatValue(field algoexplore.FieldSchema, value interface{}) string {
+	switch field.Kind {
+	case algoexplore.KindRegister:
+		n, err := strconv.ParseUint(fmt.Sprint(value), 10, 64)
+		if err != nil {
+			break
+		}
+		bits := field.Bits
+		if bits < 1 {
+			bits = 32
+		}
+		return fmt.Sprintf("0x%0*x", (bits+3)/4, n)
+	case algoexplore.KindByteArray, algoexplore.KindBitArray:
+		elems, ok := value.([]interface{})
+		if !ok {
+			break
+		}
+		cells := make([]string, len(elems))
+		for i, e := range elems {
+			switch e := e.(type) {
+			case bool:
+				cells[i] = "0"
+				if e {
+					cells[i] = "1"
+				}
+			case json.Number:
+				n, _ := strconv.ParseUint(e.String(), 10, 64)
+				cells[i] = fmt.Sprintf("%02x", n)
+			default:
+				cells[i] = fmt.Sprint(e)
+			}
+		}
+		if field.Kind == algoexplore.KindBitArray {
+			return strings.Join(cells, "")
+		}
+		return strings.Join(cells, " ")
+	case algoexplore.KindTrigger:
+		if truthy(value) {
+			return "fired"
+		}
+		return "-"
+	}
+
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// printable quotes a byte if it is printable ascii
+func printable(d byte) string {
+	if d >= 0x20 && d < 0x7f {
+		return strconv.QuoteRune(rune(d))
+	}
+	return ""
+}
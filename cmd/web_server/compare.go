@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/joekir/algoexplore/internal/algos/ctph"
+)
+
+// compareInput - one side of a comparison, as JSON 'text' in an 'encoding',
+// or JSON 'base64', the same as at init
+type compareInput struct {
+	Text     string `json:"text"`
+	Encoding string `json:"encoding"`
+	Base64   []byte `json:"base64"`
+}
+
+type compareReq struct {
+	Inputs     []compareInput `json:"inputs"`
+	Signatures []string       `json:"signatures"`
+}
+
+type compareResp struct {
+	*ctph.Comparison
+	Signatures []string `json:"signatures"`
+	// Chunks holds, for each input, the bytes behind every signature character
+	// Inputs echoes the exact bytes that were hashed, which the chunks index
+	Chunks []ctph.Chunks `json:"chunks,omitempty"`
+	Inputs [][]byte      `json:"inputs,omitempty"`
+}
+
+// Compare scores the similarity of two inputs, or two signatures, as ssdeep
+// does. The score is explained by the signature parts that were compared and
+// how they align, and when inputs are sent, by the chunk of each input that
+// produced every signature character
+func Compare(w http.ResponseWriter, r *http.Request) {
+	// two inputs, each inflated by base64, and the JSON around them
+	var c compareReq
+	if err := readJSONBody(w, r, 4*(*maxInputBytes), &c); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	var resp compareResp
+	switch {
+	case len(c.Inputs) == 2 && c.Signatures == nil:
+		for _, in := range c.Inputs {
+			input, err := decodeInput(in.Text, in.Encoding, in.Base64)
+			if err == nil {
+				err = validateInput(input)
+			}
+			if err != nil {
+				writeHTTPError(w, err)
+				return
+			}
+
+			sig, chunks, err := ctph.HashChunks(input)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
+				return
+			}
+			resp.Signatures = append(resp.Signatures, sig)
+			resp.Chunks = append(resp.Chunks, chunks)
+			resp.Inputs = append(resp.Inputs, input)
+		}
+	case len(c.Signatures) == 2 && c.Inputs == nil:
+		resp.Signatures = c.Signatures
+	default:
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidInput,
+			"Provide either two 'inputs' or two 'signatures'")
+		return
+	}
+
+	comparison, err := ctph.Explain(resp.Signatures[0], resp.Signatures[1])
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidInput,
+			fmt.Sprintf("Failed to compare signatures: %s", err.Error()))
+		return
+	}
+	resp.Comparison = comparison
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Errorf("failed to write response: %s\n", err.Error())
+	}
+}
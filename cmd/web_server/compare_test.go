@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/joekir/algoexplore/internal/algos/ctph"
+)
+
+// ctphHash returns the ssdeep signature of data, failing the test if it cannot
+func ctphHash(t *testing.T, data []byte) string {
+	t.Helper()
+
+	h, err := ctph.Hash(data)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	return h
+}
+
+func TestCompare_withTwoInputs_ExplainsTheScoreWithChunks(t *testing.T) {
+	original, err := ioutil.ReadFile("../../internal/algos/ctph/testdata/crowandthefox.txt")
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+	tweaked := strings.Replace(string(original), "vous", "tous", 1)
+
+	body, err := json.Marshal(compareReq{Inputs: []compareInput{{Base64: original}, {Text: tweaked}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := serve(t, "POST", "/ctph/compare", string(body))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v\n", rr.Code, http.StatusOK)
+	}
+
+	var resp compareResp
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedScore, err := ctph.Score(ctphHash(t, original), ctphHash(t, []byte(tweaked)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Score != expectedScore || resp.Score == 0 {
+		t.Fatalf("expected score %d, got %d", expectedScore, resp.Score)
+	}
+
+	if len(resp.Signatures) != 2 || resp.Signatures[0] != ctphHash(t, original) {
+		t.Fatalf("unexpected signatures: %v", resp.Signatures)
+	}
+	if len(resp.Pairs) != 2 || len(resp.Pairs[0].Ops) == 0 {
+		t.Fatalf("expected both signature parts to be aligned, got %+v", resp.Pairs)
+	}
+
+	if len(resp.Chunks) != 2 || len(resp.Inputs) != 2 || string(resp.Inputs[1]) != tweaked {
+		t.Fatalf("expected chunks of both inputs")
+	}
+	sig1 := strings.Split(resp.Signatures[1], ":")[1]
+	if n := len(resp.Chunks[1].Sig1); n != len(sig1) || resp.Chunks[1].Sig1[n-1].End != len(tweaked) {
+		t.Fatalf("expected a chunk per character of %s, got %+v", sig1, resp.Chunks[1].Sig1)
+	}
+}
+
+func TestCompare_withTwoSignatures_ReturnsOnlyTheScore(t *testing.T) {
+	rr := serve(t, "POST", "/ctph/compare",
+		`{"signatures":["48:ABCDEFGH:ABCDEFGHIJ","48:ABCDEFGX:ABCDEFGHXIJ"]}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v\n", rr.Code, http.StatusOK)
+	}
+
+	var resp compareResp
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Score != 96 || len(resp.Pairs) != 2 || resp.Chunks != nil || resp.Inputs != nil {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestCompare_withInvalidRequests_ReturnsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"signatures":["3:a:b"]}`, http.StatusUnprocessableEntity, codeInvalidInput},
+		{`{"inputs":[{"text":"a"},{"text":"b"}],"signatures":["3:a:b","3:a:b"]}`, http.StatusUnprocessableEntity, codeInvalidInput},
+		{`{"signatures":["3ab","3:a:b"]}`, http.StatusUnprocessableEntity, codeInvalidInput},
+		{`{"inputs":[{"text":"a"},{}]}`, http.StatusUnprocessableEntity, codeInvalidInput},
+		{`{"inputs":[{"text":"a","base64":"YQ=="},{"text":"b"}]}`, http.StatusUnprocessableEntity, codeInvalidInput},
+		{`{"unknown":1}`, http.StatusBadRequest, codeBadRequest},
+	} {
+		rr := serve(t, "POST", "/ctph/compare", tc.body)
+		expectError(t, rr, tc.status, tc.code)
+	}
+}
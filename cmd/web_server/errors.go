@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/glog"
+)
+
+// Machine readable codes returned in every JSON error body
+const (
+	codeUnknownAlgo   = "unknown_algo"
+	codeNoSchema      = "no_schema"
+	codeBadRequest    = "bad_request"
+	codeInvalidInput  = "invalid_input"
+	codeInvalidState  = "invalid_state"
+	codeNoSession     = "no_session"
+	codeAlgoFailed    = "algo_failed"
+	codeAlgoDone      = "algo_done"
+	codeTraceMismatch = "trace_mismatch"
+	codeInternal      = "internal_error"
+)
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type errorResp struct {
+	Error apiError `json:"error"`
+}
+
+// httpError - an error carrying the status and code it should be replied with
+type httpError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+// writeHTTPError replies with err's status and code if it is an *httpError,
+// otherwise it is treated as an internal error
+func writeHTTPError(w http.ResponseWriter, err error) {
+	if e, ok := err.(*httpError); ok {
+		writeError(w, e.status, e.code, e.message)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+}
+
+// writeError replies with a JSON error body, use it in place of http.Error
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	if status >= http.StatusInternalServerError {
+		glog.Errorf("%d %s: %s\n", status, code, message)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(errorResp{apiError{Code: code, Message: message}}); err != nil {
+		glog.Errorf("failed to write error body: %s\n", err.Error())
+	}
+}
+
+// recoverPanics is middleware that turns a panicking handler, or plugin, into
+// a 500 for that one request rather than letting it take down the server
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal,
+					fmt.Sprintf("recovered from panic: %v", p))
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
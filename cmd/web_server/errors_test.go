@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joekir/algoexplore"
+)
+
+// panicky - a legacy plugin that is only registered for the tests, which
+// panics whenever it is stepped
+type panicky struct{}
+
+func (p *panicky) Name() string                        { return "panicky" }
+func (p *panicky) Init(inputLen int)                   {}
+func (p *panicky) Step(d byte)                         { panic("panicky plugin") }
+func (p *panicky) SerializeState() string              { return "{}" }
+func (p *panicky) DeserializeState(state string) error { return nil }
+
+func init() {
+	algoexplore.RegisterLegacy(func() algoexplore.LegacyAlgoPlugin { return &panicky{} })
+}
+
+func serve(t *testing.T, method, url, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+	return rr
+}
+
+func expectError(t *testing.T, rr *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if rr.Code != status {
+		t.Fatalf("handler returned wrong status code: got %v want %v\n", rr.Code, status)
+	}
+
+	if ctype := rr.Header().Get("Content-Type"); ctype != "application/json" {
+		t.Fatalf("handler returned wrong content type: got %v want %v\n", ctype, "application/json")
+	}
+
+	var resp errorResp
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode error body: %s", err.Error())
+	}
+
+	if resp.Error.Code != code {
+		t.Fatalf("expected error code %s, got %s", code, resp.Error.Code)
+	}
+}
+
+func TestInit_withUnknownAlgo_Returns404(t *testing.T) {
+	rr := serve(t, "POST", "/nonexistent/init", `{"data_length": 10}`)
+	expectError(t, rr, http.StatusNotFound, codeUnknownAlgo)
+}
+
+func TestStepAlgo_withUnknownAlgo_Returns404(t *testing.T) {
+	rr := serve(t, "POST", "/nonexistent/step", `{"byte": 103}`)
+	expectError(t, rr, http.StatusNotFound, codeUnknownAlgo)
+}
+
+func TestInit_withMalformedJSON_Returns400(t *testing.T) {
+	rr := serve(t, "POST", "/ctph/init", `{"data_length": `)
+	expectError(t, rr, http.StatusBadRequest, codeBadRequest)
+}
+
+func TestInit_withUnknownField_Returns400(t *testing.T) {
+	rr := serve(t, "POST", "/ctph/init", `{"data_length": 10, "bogus": true}`)
+	expectError(t, rr, http.StatusBadRequest, codeBadRequest)
+}
+
+func TestInit_withInvalidLength_Returns422(t *testing.T) {
+	rr := serve(t, "POST", "/ctph/init", `{"data_length": 0}`)
+	expectError(t, rr, http.StatusUnprocessableEntity, codeInvalidInput)
+}
+
+func TestStepAlgo_withoutSession_Returns428(t *testing.T) {
+	rr := serve(t, "POST", "/ctph/step", `{"byte": 103}`)
+	expectError(t, rr, http.StatusPreconditionRequired, codeNoSession)
+}
+
+func TestStepAlgo_withCorruptedState_Returns422(t *testing.T) {
+	cookie := initCtphSession(t, 10)
+
+	req, err := http.NewRequest("POST", "/ctph/step", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(cookie)
+
+	c, err := cookieStore.Get(req, sessionCookieName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt := []byte(`{"algo":"ctph","state":"{\"not_a_ctph_field\":1}"}`)
+	if err := stateStore.Set(c.Values[sessionIDKey].(string), corrupt); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := serve(t, "POST", "/ctph/step", `{"byte": 103}`, cookie)
+	expectError(t, rr, http.StatusUnprocessableEntity, codeInvalidState)
+}
+
+func TestStepAlgo_withPanickingPlugin_FailsTheSessionAndKeepsServing(t *testing.T) {
+	rr := serve(t, "POST", "/panicky/init", `{"data_length": 10}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("init returned wrong status code: got %v want %v\n", rr.Code, http.StatusCreated)
+	}
+	cookie := rr.Result().Cookies()[0]
+
+	rr = serve(t, "POST", "/panicky/step", `{"byte": 103}`, cookie)
+	expectError(t, rr, http.StatusInternalServerError, codeAlgoFailed)
+
+	// the server is still alive for everyone else
+	if rr := serve(t, "POST", "/ctph/init", `{"data_length": 10}`); rr.Code != http.StatusCreated {
+		t.Fatalf("init returned wrong status code: got %v want %v\n", rr.Code, http.StatusCreated)
+	}
+}
+
+func TestSchema_withoutStateDescriber_Returns404(t *testing.T) {
+	rr := serve(t, "GET", "/fakesum/schema", "")
+	expectError(t, rr, http.StatusNotFound, codeNoSchema)
+}
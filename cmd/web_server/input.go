@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/joekir/algoexplore"
+)
+
+const (
+	// defaultMaxInputBytes caps the size of an input uploaded at init,
+	// unless overridden by the max_input_bytes flag
+	defaultMaxInputBytes = 1 << 20
+
+	inputFormField   = "file"
+	optionsFormField = "options"
+)
+
+type hashReq struct {
+	DataLength int    `json:"data_length"`
+	Text       string `json:"text"`
+	Encoding   string `json:"encoding"`
+	Base64     []byte `json:"base64"`
+
+	Options json.RawMessage `json:"options"`
+}
+
+// readInitReq parses an init request, which either declares only the length
+// of the input that will be stepped byte by byte, or uploads the whole input.
+// The input may be JSON 'text' in an 'encoding' (see algoexplore.Encodings),
+// JSON 'base64', or a multipart/form-data file.
+// Either may carry the algorithm's JSON 'options', see algoexplore.OptionsInitializer.
+// input is nil if only the length was declared, options is nil if none were sent
+func readInitReq(w http.ResponseWriter, r *http.Request) (inputLen int, input []byte, options json.RawMessage, err error) {
+	if isMultipart(r) {
+		input, options, err = readUpload(w, r)
+		return len(input), input, options, err
+	}
+
+	// base64 inflates the input by a third, leave room for that and the JSON
+	var h hashReq
+	if err := readJSONBody(w, r, 2*(*maxInputBytes), &h); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if input, err = decodeInput(h.Text, h.Encoding, h.Base64); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if input == nil {
+		if h.DataLength <= 0 {
+			return 0, nil, nil, &httpError{http.StatusUnprocessableEntity, codeInvalidInput, "Invalid 'data_length'"}
+		}
+		return h.DataLength, nil, h.Options, nil
+	}
+
+	if err := validateInput(input); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if h.DataLength != 0 && h.DataLength != len(input) {
+		return 0, nil, nil, &httpError{http.StatusUnprocessableEntity, codeInvalidInput,
+			fmt.Sprintf("'data_length' %d does not match the %d bytes of input", h.DataLength, len(input))}
+	}
+
+	return len(input), input, h.Options, nil
+}
+
+// readJSONBody strictly decodes a JSON body of at most limit bytes into v
+func readJSONBody(w http.ResponseWriter, r *http.Request, limit int64, v interface{}) error {
+	limited := limitBody(w, r, limit)
+
+	var body io.Reader = r.Body
+	if err := algoexplore.StrictUnmarshalJSON(&body, v); err != nil {
+		if limited.exceeded {
+			return &httpError{http.StatusRequestEntityTooLarge, codeInvalidInput, errBodyTooLarge.Error()}
+		}
+		return &httpError{http.StatusBadRequest, codeBadRequest, err.Error()}
+	}
+	return nil
+}
+
+// errBodyTooLarge is returned reading past the limit of a limitedBody
+var errBodyTooLarge = errors.New("http: request body too large")
+
+// limitedBody - a request body that fails once more than its limit is read,
+// recording that it did, as the errors of the decoders reading it may not wrap
+// the one it returned
+type limitedBody struct {
+	io.ReadCloser
+	w        http.ResponseWriter
+	left     int64
+	exceeded bool
+}
+
+// limitBody replaces the body of r with a limitedBody of at most limit bytes
+func limitBody(w http.ResponseWriter, r *http.Request, limit int64) *limitedBody {
+	b := &limitedBody{ReadCloser: r.Body, w: w, left: limit}
+	r.Body = b
+	return b
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errBodyTooLarge
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// read one byte more than is left, to tell a body that ends at the limit
+	// from one that goes past it
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.left {
+		b.left -= int64(n)
+		return n, err
+	}
+
+	n, b.left, b.exceeded = int(b.left), 0, true
+	// the rest of the body is left unread, so the connection can't be reused
+	b.w.Header().Set("Connection", "close")
+	return n, errBodyTooLarge
+}
+
+// decodeInput returns the bytes of JSON 'text' in an 'encoding', or of JSON
+// 'base64', input is nil if neither was sent
+func decodeInput(text, encoding string, b64 []byte) (input []byte, err error) {
+	switch {
+	case len(text) > 0 && b64 != nil:
+		return nil, &httpError{http.StatusUnprocessableEntity, codeInvalidInput,
+			"Only one of 'text' or 'base64' may be provided"}
+	case len(text) > 0:
+		if input, err = algoexplore.EncodeInput(text, encoding); err != nil {
+			return nil, &httpError{http.StatusUnprocessableEntity, codeInvalidInput, err.Error()}
+		}
+	case b64 != nil:
+		input = b64
+	}
+
+	if len(encoding) > 0 && len(text) < 1 {
+		return nil, &httpError{http.StatusUnprocessableEntity, codeInvalidInput,
+			"'encoding' only applies to 'text'"}
+	}
+	return input, nil
+}
+
+func isMultipart(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "multipart/form-data"
+}
+
+// readUpload reads the input from the 'file' of a multipart/form-data request,
+// and the algorithm's JSON 'options' if the form has them
+func readUpload(w http.ResponseWriter, r *http.Request) ([]byte, json.RawMessage, error) {
+	if !isMultipart(r) {
+		return nil, nil, &httpError{http.StatusUnsupportedMediaType, codeBadRequest,
+			"Expected a multipart/form-data upload"}
+	}
+
+	// leave room for the multipart headers and boundaries around the file
+	limited := limitBody(w, r, *maxInputBytes+64<<10)
+	if err := r.ParseMultipartForm(*maxInputBytes); err != nil {
+		if limited.exceeded {
+			return nil, nil, &httpError{http.StatusRequestEntityTooLarge, codeInvalidInput, errBodyTooLarge.Error()}
+		}
+		return nil, nil, &httpError{http.StatusBadRequest, codeBadRequest, err.Error()}
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	f, _, err := r.FormFile(inputFormField)
+	if err != nil {
+		return nil, nil, &httpError{http.StatusBadRequest, codeBadRequest,
+			fmt.Sprintf("missing '%s' in upload: %s", inputFormField, err.Error())}
+	}
+	defer f.Close()
+
+	input, err := ioutil.ReadAll(io.LimitReader(f, *maxInputBytes+1))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := validateInput(input); err != nil {
+		return nil, nil, err
+	}
+
+	var options json.RawMessage
+	if o := r.FormValue(optionsFormField); len(o) > 0 {
+		options = json.RawMessage(o)
+	}
+	return input, options, nil
+}
+
+func validateInput(input []byte) error {
+	if len(input) < 1 {
+		return &httpError{http.StatusUnprocessableEntity, codeInvalidInput, "Empty input"}
+	}
+
+	if int64(len(input)) > *maxInputBytes {
+		return &httpError{http.StatusRequestEntityTooLarge, codeInvalidInput,
+			fmt.Sprintf("Input exceeds the maximum of %d bytes", *maxInputBytes)}
+	}
+	return nil
+}
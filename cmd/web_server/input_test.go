@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/joekir/algoexplore"
+	"github.com/joekir/algoexplore/internal/algos/ctph"
+)
+
+func initWithInput(t *testing.T, body string) (*http.Cookie, initResp) {
+	t.Helper()
+
+	rr := serve(t, "POST", "/ctph/init", body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("init returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusCreated, rr.Body)
+	}
+
+	var resp initResp
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+	return rr.Result().Cookies()[0], resp
+}
+
+func TestStepsAlgo_withInputUploadedAtInit_RunsTheRetryPassToCompletion(t *testing.T) {
+	// a constant input never triggers, so the first block size guess of 6
+	// produces too short a signature and ctph must retry the input at 3
+	text := strings.Repeat("a", 300)
+	cookie, resp := initWithInput(t, fmt.Sprintf(`{"text": %q}`, text))
+
+	if resp.Cursor != 0 {
+		t.Fatalf("expected to start at cursor 0, got %d", resp.Cursor)
+	}
+
+	rr := serve(t, "POST", "/ctph/steps", fmt.Sprintf(`{"count": %d}`, maxBatchSteps), cookie)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("steps returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusOK, rr.Body)
+	}
+
+	var steps stepsResp
+	if err := json.NewDecoder(rr.Body).Decode(&steps); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+
+	// each pass steps every byte, plus one step to complete the pass
+	if steps.Count != 2*(len(text)+1) {
+		t.Fatalf("expected 2 passes of %d steps, got %d steps", len(text)+1, steps.Count)
+	}
+
+	if !strings.Contains(steps.State, `"block_size":3`) || !strings.Contains(steps.State, `"retry":false`) {
+		t.Fatalf("expected a completed hash at block size 3, got %s", steps.State)
+	}
+
+	if expected := ctphHash(t, []byte(text)); !steps.Done || steps.Output != expected || steps.Outputs["block_size"] != "3" {
+		t.Fatalf("expected to be done with the output %s, got %t %s %v", expected, steps.Done, steps.Output, steps.Outputs)
+	}
+
+	// a done algorithm takes no more steps
+	rr = serve(t, "POST", "/ctph/step", "", cookie)
+	expectError(t, rr, http.StatusConflict, codeAlgoDone)
+
+	rr = serve(t, "POST", "/ctph/steps", `{"count": 1}`, cookie)
+	expectError(t, rr, http.StatusConflict, codeAlgoDone)
+}
+
+func TestStepAlgo_withInputUploadedAtInit_AdvancesTheCursor(t *testing.T) {
+	cookie, _ := initWithInput(t, `{"base64": "Z2hp"}`)
+
+	for i, window := range []string{`[103,0`, `[103,104,0`, `[103,104,105,0`} {
+		rr := serve(t, "POST", "/ctph/step", "{}", cookie)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("step returned wrong status code: got %v want %v\n", rr.Code, http.StatusOK)
+		}
+
+		var resp stateResp
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %s", err.Error())
+		}
+
+		if resp.Cursor != i+1 || !strings.Contains(resp.State, `"window":`+window) {
+			t.Fatalf("step %d: expected cursor %d and window %s, got %d and %s", i, i+1, window, resp.Cursor, resp.State)
+		}
+	}
+
+	rr := serve(t, "POST", "/ctph/step", `{"byte": 103}`, cookie)
+	expectError(t, rr, http.StatusUnprocessableEntity, codeInvalidInput)
+}
+
+// uploadReq builds a multipart upload of data, with the options form field
+// if any are given
+func uploadReq(t *testing.T, url string, data []byte, options ...string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile(inputFormField, "sample.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range options {
+		if err := mw.WriteField(optionsFormField, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", url, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestInit_withMultipartUpload_StoresTheFile(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, uploadReq(t, "/ctph/init", []byte{0x00, 0xff, 0x10}))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("init returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusCreated, rr.Body)
+	}
+
+	if !strings.Contains(rr.Body.String(), `\"input_length\":3`) {
+		t.Fatalf("expected an input length of 3, got %s", rr.Body)
+	}
+
+	// NUL is a legitimate byte of an uploaded input
+	rr = serve(t, "POST", "/ctph/step", "{}", rr.Result().Cookies()[0])
+	if !strings.Contains(rr.Body.String(), `\"index\":0`) {
+		t.Fatalf("expected the first byte to be stepped, got %s", rr.Body)
+	}
+}
+
+func TestUpload_withBinaryFile_StepsRawBytes(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, uploadReq(t, "/ctph/upload", []byte{0xde, 0xad, 0xbe, 0xef}))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("upload returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusCreated, rr.Body)
+	}
+
+	rr = serve(t, "POST", "/ctph/steps", `{"count": 4}`, rr.Result().Cookies()[0])
+	if !strings.Contains(rr.Body.String(), `\"window\":[222,173,190,239`) {
+		t.Fatalf("expected the raw bytes in the window, got %s", rr.Body)
+	}
+}
+
+func TestUpload_withoutMultipart_Returns415(t *testing.T) {
+	rr := serve(t, "POST", "/ctph/upload", `{"text": "abc"}`)
+	expectError(t, rr, http.StatusUnsupportedMediaType, codeBadRequest)
+}
+
+func TestUpload_overTheConfiguredLimit_Returns413(t *testing.T) {
+	defer func(limit int64) { *maxInputBytes = limit }(*maxInputBytes)
+	*maxInputBytes = 4
+
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, uploadReq(t, "/ctph/upload", []byte("12345")))
+	expectError(t, rr, http.StatusRequestEntityTooLarge, codeInvalidInput)
+}
+
+func TestLimitBody_atAndPastTheLimit(t *testing.T) {
+	for _, tc := range []struct {
+		body     string
+		exceeded bool
+	}{
+		{"1234", false},
+		{"12345", true},
+	} {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tc.body))
+		limited := limitBody(rr, r, 4)
+
+		data, err := ioutil.ReadAll(r.Body)
+		if limited.exceeded != tc.exceeded || (err == errBodyTooLarge) != tc.exceeded {
+			t.Fatalf("%q: expected exceeded %v, got %v: %v", tc.body, tc.exceeded, limited.exceeded, err)
+		}
+		if string(data) != tc.body[:4] {
+			t.Fatalf("%q: expected to read up to the limit, got %q", tc.body, data)
+		}
+		if closed := rr.Header().Get("Connection") == "close"; closed != tc.exceeded {
+			t.Fatalf("%q: expected the connection closed %v, got %v", tc.body, tc.exceeded, closed)
+		}
+	}
+}
+
+func TestInit_withInvalidInputs_ReturnsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"text": "abc", "data_length": 4}`, http.StatusUnprocessableEntity, codeInvalidInput},
+		{`{"text": "abc", "base64": "YWJj"}`, http.StatusUnprocessableEntity, codeInvalidInput},
+		{`{"base64": "not base64!"}`, http.StatusBadRequest, codeBadRequest},
+		{fmt.Sprintf(`{"text": %q}`, strings.Repeat("a", defaultMaxInputBytes+1)), http.StatusRequestEntityTooLarge, codeInvalidInput},
+	} {
+		rr := serve(t, "POST", "/ctph/init", tc.body)
+		expectError(t, rr, tc.status, tc.code)
+	}
+}
+
+func TestInit_withMultiByteText_StepsTheEncodedBytesThroughCtph(t *testing.T) {
+	text := "Fuzzy Wüzzy was a bear 🐻, Fuzzy Wüzzy had no hair"
+
+	for _, tc := range []struct {
+		encoding string
+		expected []byte
+	}{
+		{"utf-8", []byte(text)},
+		{"utf-16le", mustEncode(t, text, "utf-16le")},
+	} {
+		cookie, resp := initWithInput(t, fmt.Sprintf(`{"text": %q, "encoding": %q}`, text, tc.encoding))
+
+		if !bytes.Equal(resp.Input, tc.expected) {
+			t.Fatalf("%s: expected the input bytes %x, got %x", tc.encoding, tc.expected, resp.Input)
+		}
+
+		rr := serve(t, "POST", "/ctph/steps", fmt.Sprintf(`{"count": %d}`, maxBatchSteps), cookie)
+		var steps stepsResp
+		if err := json.NewDecoder(rr.Body).Decode(&steps); err != nil {
+			t.Fatalf("failed to decode response: %s", err.Error())
+		}
+
+		expected := new(ctph.Ctph)
+		expected.Init(len(tc.expected))
+		for pos := 0; pos <= len(tc.expected); pos = expected.Position() {
+			var d byte
+			if pos < len(tc.expected) {
+				d = tc.expected[pos]
+			}
+			expected.Step(d)
+		}
+
+		state, err := expected.SerializeState()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if steps.State != state {
+			t.Fatalf("%s: expected the state of hashing the encoded bytes\n%s\ngot\n%s",
+				tc.encoding, state, steps.State)
+		}
+	}
+}
+
+func TestInit_withTextNotRepresentableInEncoding_Returns422(t *testing.T) {
+	rr := serve(t, "POST", "/ctph/init", `{"text": "🐻", "encoding": "latin-1"}`)
+	expectError(t, rr, http.StatusUnprocessableEntity, codeInvalidInput)
+
+	rr = serve(t, "POST", "/ctph/init", `{"data_length": 3, "encoding": "latin-1"}`)
+	expectError(t, rr, http.StatusUnprocessableEntity, codeInvalidInput)
+}
+
+func mustEncode(t *testing.T, text, encoding string) []byte {
+	t.Helper()
+
+	b, err := algoexplore.EncodeInput(text, encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestInit_withOptions_StoresThemInTheState(t *testing.T) {
+	_, resp := initWithInput(t, `{"text": "abc", "options": {"window_size": 9, "alphabet": "0123456789abcdef"}}`)
+
+	if !strings.Contains(resp.State, `"window_size":9`) || !strings.Contains(resp.State, `"alphabet":"0123456789abcdef"`) {
+		t.Fatalf("expected the params in the state, got %s", resp.State)
+	}
+	if !strings.Contains(resp.State, `"window":[0,0,0,0,0,0,0,0,0]`) {
+		t.Fatalf("expected a 9 byte window, got %s", resp.State)
+	}
+}
+
+func TestInit_withInvalidOptions_Returns422(t *testing.T) {
+	for _, tc := range []struct {
+		url, body string
+	}{
+		{"/ctph/init", `{"text": "abc", "options": {"window_size": 0}}`},
+		{"/ctph/init", `{"text": "abc", "options": {"unknown": 1}}`},
+		{"/ctph/init", `{"text": "abc", "options": {"window_size": "9"}}`},
+		{"/ctph/init", `{"text": "abc", "options": {"alphabet": "AA"}}`},
+		{"/ctph/init", `{"text": "abc", "options": [7]}`},
+		{"/rollinghash/init", `{"text": "abc", "options": {"window_size": 9}}`},
+	} {
+		rr := serve(t, "POST", tc.url, tc.body)
+		expectError(t, rr, http.StatusUnprocessableEntity, codeInvalidInput)
+	}
+}
+
+func TestUpload_withOptionsFormField_StoresThemInTheState(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, uploadReq(t, "/ctph/upload", []byte("abc"), `{"signature_length": 8}`))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("upload returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusCreated, rr.Body)
+	}
+
+	if !strings.Contains(rr.Body.String(), `\"signature_length\":8`) {
+		t.Fatalf("expected the options in the state, got %s", rr.Body)
+	}
+}
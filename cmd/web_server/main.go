@@ -3,34 +3,81 @@ package main
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"time"
 
 	"github.com/golang/glog"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
 	"github.com/joekir/algoexplore"
-	"github.com/joekir/algoexplore/internal/algos/ctph"
+	_ "github.com/joekir/algoexplore/internal/algos/ctph"
+	"github.com/joekir/algoexplore/internal/session"
 )
 
 const (
-	algoState                  = "ALGO_STATE"
+	sessionIDKey               = "SESSION_ID"
 	sessionCookieName          = "SESSION"
 	portEnvVarName             = "PORT"
 	cookieSessionKeyEnvVarName = "COOKIE_SESSION_KEY"
+	sessionStoreEnvVarName     = "SESSION_STORE"
+	sessionDirEnvVarName       = "SESSION_DIR"
+	maxInputBytesEnvVarName    = "MAX_INPUT_BYTES"
+
+	defaultSessionTTL = time.Hour
+
+	// maxBatchSteps caps how many bytes a single call to /{algo}/steps may advance
+	maxBatchSteps = 4096
+
+	// historySize is how many recent states are kept for stepping backwards,
+	// older positions are replayed from a checkpoint kept every historyCheckpointEvery steps
+	historySize            = 64
+	historyCheckpointEvery = 256
 )
 
 var (
 	listeningPort  = os.Getenv(portEnvVarName)
 	availableAlgos []string
 	cookieStore    *sessions.CookieStore
+	stateStore     session.Store
+	// inputStore keeps each session's input apart from its state, by session ID
+	inputStore session.Store
+
+	sessionBackend = flag.String("session_store", envOrDefault(sessionStoreEnvVarName, "memory"),
+		"where algorithm state is kept between requests: memory or file")
+	sessionDir = flag.String("session_dir", envOrDefault(sessionDirEnvVarName,
+		path.Join(os.TempDir(), "algoexplore-sessions")), "directory used by the file session store")
+	sessionTTL = flag.Duration("session_ttl", defaultSessionTTL, "idle time after which a session is evicted")
+
+	maxInputBytes = flag.Int64("max_input_bytes", envInt64OrDefault(maxInputBytesEnvVarName, defaultMaxInputBytes),
+		"largest input, in bytes, that may be uploaded at init")
+	maxTraceBytes = flag.Int64("max_trace_bytes", defaultMaxTraceBytes,
+		"largest trace, in bytes, that may be loaded")
 )
 
 func init() {
 	cookieStore = sessions.NewCookieStore([]byte(os.Getenv(cookieSessionKeyEnvVarName)))
+	stateStore = session.NewMemoryStore(defaultSessionTTL)
+	inputStore = session.NewMemoryStore(defaultSessionTTL)
+}
+
+func envOrDefault(name, def string) string {
+	if v := os.Getenv(name); len(v) > 0 {
+		return v
+	}
+	return def
+}
+
+func envInt64OrDefault(name string, def int64) int64 {
+	v, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil {
+		return def
+	}
+	return v
 }
 
 func main() {
@@ -38,15 +85,29 @@ func main() {
 		listeningPort = "8080"
 	}
 
-	router := mux.NewRouter()
-	router.HandleFunc("/{algo}/init", Init).Methods("POST")
-	router.HandleFunc("/{algo}/step", StepAlgo).Methods("POST")
+	router := newRouter()
 	workingDir, err := os.Getwd()
 
 	flag.Parse()
 	if err != nil {
 		glog.Fatal(err)
 	}
+
+	stateStore, err = session.New(*sessionBackend, *sessionDir, *sessionTTL)
+	if err != nil {
+		glog.Fatal(err)
+	}
+	inputStore, err = session.New(*sessionBackend, path.Join(*sessionDir, "input"), *sessionTTL)
+	if err != nil {
+		glog.Fatal(err)
+	}
+
+	if *maxInputBytes < 1 {
+		glog.Fatalf("invalid max_input_bytes: %d", *maxInputBytes)
+	}
+	if *maxTraceBytes < 1 {
+		glog.Fatalf("invalid max_trace_bytes: %d", *maxTraceBytes)
+	}
 	staticDir := path.Join(workingDir, "/static/")
 	router.PathPrefix("/").Handler(http.FileServer(http.Dir(staticDir)))
 
@@ -60,79 +121,264 @@ func main() {
 	glog.Fatal(server.ListenAndServe())
 }
 
-type hashReq struct {
-	DataLength int `json:"data_length"`
+// newRouter wires up the API routes, every registered algorithm is served
+// under its own name as /{algo}/...
+func newRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(recoverPanics)
+	router.HandleFunc("/algos", ListAlgos).Methods("GET")
+	router.HandleFunc("/ctph/compare", Compare).Methods("POST")
+	router.HandleFunc("/{algo}/schema", Schema).Methods("GET")
+	router.HandleFunc("/{algo}/options", Options).Methods("GET")
+	router.Handle("/{algo}/init", lockSession(Init)).Methods("POST")
+	router.Handle("/{algo}/upload", lockSession(Upload)).Methods("POST")
+	router.Handle("/{algo}/step", lockSession(StepAlgo)).Methods("POST")
+	router.Handle("/{algo}/steps", lockSession(StepsAlgo)).Methods("POST")
+	router.Handle("/{algo}/back", lockSession(BackAlgo)).Methods("POST")
+	router.Handle("/{algo}/seek", lockSession(SeekAlgo)).Methods("POST")
+	router.HandleFunc("/{algo}/trace", Trace).Methods("GET")
+	router.Handle("/{algo}/trace", lockSession(LoadTrace)).Methods("POST")
+	return router
+}
+
+// ListAlgos describes every registered algorithm
+func ListAlgos(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(algoexplore.AlgoInfos()); err != nil {
+		glog.Errorf("failed to write response: %s\n", err.Error())
+	}
+}
+
+// Schema describes the algorithm's serialized state so it can be drawn generically
+func Schema(w http.ResponseWriter, r *http.Request) {
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
+		return
+	}
+
+	schema, ok := algoexplore.SchemaOf(algo)
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNoSchema,
+			fmt.Sprintf("algo does not describe its state: %s", algo.Name()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(schema); err != nil {
+		glog.Errorf("failed to write response: %s\n", err.Error())
+	}
 }
 
-func validateAlgo(vars map[string]string) *algoexplore.AlgoPlugin {
-	algoName := vars["algo"]
+// Options describes the options the algorithm takes at init, so a form can be
+// drawn for them generically. An algorithm that takes none has no options.
+func Options(w http.ResponseWriter, r *http.Request) {
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
+		return
+	}
+
+	schema, _ := algoexplore.OptionsOf(algo)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(schema); err != nil {
+		glog.Errorf("failed to write response: %s\n", err.Error())
+	}
+}
 
-	algo, err := algoexplore.GetAlgo(algoName)
+// validateAlgo looks up the algorithm named in the route, replying with a 404
+// if it is not registered
+func validateAlgo(w http.ResponseWriter, vars map[string]string) (algoexplore.AlgoPlugin, bool) {
+	algo, err := algoexplore.GetAlgo(vars["algo"])
 	if err != nil {
-		glog.Fatal("valid algorithm not found")
-		return nil
+		writeError(w, http.StatusNotFound, codeUnknownAlgo, err.Error())
+		return nil, false
 	}
 
-	return &algo
+	return algo, true
+}
+
+// validateSession loads the client's session for the algorithm, replying with
+// a 428 if there is none
+func validateSession(w http.ResponseWriter, r *http.Request, algo algoexplore.AlgoPlugin) (*sessions.Session, *algoSession, bool) {
+	cookie, sess, err := loadSession(r, algo.Name())
+	if err == session.ErrNotFound {
+		writeError(w, http.StatusPreconditionRequired, codeNoSession, "no session detected")
+		return nil, nil, false
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return nil, nil, false
+	}
+
+	return cookie, sess, true
 }
 
 func Init(w http.ResponseWriter, r *http.Request) {
-	algo := validateAlgo(mux.Vars(r))
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
+		return
+	}
 
-	var h hashReq
-	var body io.Reader = r.Body
-	if err := algoexplore.StrictUnmarshalJSON(&body, &h); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	inputLen, input, options, err := readInitReq(w, r)
+	if err != nil {
+		writeHTTPError(w, err)
 		return
 	}
 
-	if h.DataLength <= 0 {
-		http.Error(w, "Invalid 'data_length'", http.StatusUnprocessableEntity)
+	startSession(w, r, algo, inputLen, input, options)
+}
+
+// Upload initializes the algorithm with a file sent as multipart/form-data,
+// which lets arbitrary binary input be stepped
+func Upload(w http.ResponseWriter, r *http.Request) {
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
 		return
 	}
 
-	// A session is always returned
-	session, _ := cookieStore.Get(r, sessionCookieName)
+	input, options, err := readUpload(w, r)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
 
-	if !session.IsNew {
-		glog.Infoln("Deleting old cookie")
-		session.Options.MaxAge = -1
-		if err := session.Save(r, w); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	startSession(w, r, algo, len(input), input, options)
+}
 
-		var err error
-		session, err = cookieStore.New(r, sessionCookieName)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+// startSession initializes the algorithm and replaces the client's session
+// input is nil if the client will send the input byte by byte
+func startSession(w http.ResponseWriter, r *http.Request, algo algoexplore.AlgoPlugin, inputLen int, input []byte, options json.RawMessage) {
+	glog.Infof("registering %s algorithm\n", algo.Name())
+	if err := algoexplore.InitAlgo(algo, inputLen, options); err != nil {
+		if _, ok := err.(*algoexplore.PanicError); ok {
+			writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
 			return
 		}
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidInput, "Failed to initialize: "+err.Error())
+		return
 	}
 
-	// https://golang.org/doc/effective_go.html#type_switch
-	var state string
-	switch algo := (*algo).(type) {
-	case *ctph.Ctph:
-		algo.Init(h.DataLength)
-		state = algo.SerializeState()
-		glog.Infoln("registering ctph algorithm")
-	default:
-		glog.Fatal("Unable to concretize algo type")
+	state, err := algo.SerializeState()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
+		return
 	}
+
 	glog.Infof("state: %#v\n", state)
 
-	session.Values[algoState] = state
-	if err := session.Save(r, w); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	sess := &algoSession{
+		Algo:     algo.Name(),
+		State:    state,
+		Input:    input,
+		History:  algoexplore.NewHistory(historySize, historyCheckpointEvery, state),
+		InputLen: inputLen,
+		Options:  options,
+	}
+	if !replaceSession(w, r, sess) {
+		return
+	}
+
+	st, err := newStateResp(algo, sess)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
 		return
 	}
 
+	// echo the exact bytes that will be stepped, so the client never has to
+	// guess how its text was encoded
+	resp := initResp{st, input}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(state); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Errorf("failed to write response: %s\n", err.Error())
+	}
+}
+
+// replaceSession discards the client's session for the new one, replying
+// with an error if it could not be saved
+func replaceSession(w http.ResponseWriter, r *http.Request, sess *algoSession) bool {
+	cookie, err := newSession(r)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return false
+	}
+
+	if err := saveInput(cookie, sess); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return false
 	}
+	if err := saveSession(w, r, cookie, sess); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return false
+	}
+	return true
+}
+
+type initResp struct {
+	stateResp
+	Input []byte `json:"input,omitempty"`
+}
+
+// stateResp - the algorithm's state after a request, the index of the input
+// byte it will consume next, and its output so far which is final once done
+type stateResp struct {
+	State   string            `json:"state"`
+	Cursor  int               `json:"cursor"`
+	Done    bool              `json:"done"`
+	Output  string            `json:"output"`
+	Outputs map[string]string `json:"outputs,omitempty"`
+}
+
+// newStateResp describes the session's state, the algorithm must already hold
+// that state
+func newStateResp(algo algoexplore.AlgoPlugin, sess *algoSession) (stateResp, error) {
+	output, err := algo.Output()
+	if err != nil {
+		return stateResp{}, err
+	}
+
+	outputs, err := algoexplore.NamedOutputsOf(algo)
+	if err != nil {
+		return stateResp{}, err
+	}
+
+	return stateResp{
+		State:   sess.State,
+		Cursor:  algoexplore.Cursor(algo, sess.History.Pos),
+		Done:    algo.Done(),
+		Output:  output,
+		Outputs: outputs,
+	}, nil
+}
+
+// writeState replies with the session's state, the algorithm must already
+// hold that state
+func writeState(w http.ResponseWriter, status int, algo algoexplore.AlgoPlugin, sess *algoSession) {
+	resp, err := newStateResp(algo, sess)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Errorf("failed to write response: %s\n", err.Error())
+	}
+}
+
+// rejectDone replies with a 409 if the algorithm is done, as a done algorithm
+// takes no more steps
+func rejectDone(w http.ResponseWriter, algo algoexplore.AlgoPlugin) bool {
+	if !algo.Done() {
+		return false
+	}
+
+	writeError(w, http.StatusConflict, codeAlgoDone,
+		"The algorithm is done, initialize it again to start over")
+	return true
 }
 
 type stepReq struct {
@@ -140,15 +386,13 @@ type stepReq struct {
 }
 
 func StepAlgo(w http.ResponseWriter, r *http.Request) {
-	algoInfo := validateAlgo(mux.Vars(r))
-	session, err := cookieStore.Get(r, sessionCookieName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
 		return
 	}
 
-	if session.IsNew {
-		http.Error(w, "no session detected", http.StatusPreconditionRequired)
+	cookie, sess, ok := validateSession(w, r, algo)
+	if !ok {
 		return
 	}
 
@@ -156,45 +400,246 @@ func StepAlgo(w http.ResponseWriter, r *http.Request) {
 	decoder.DisallowUnknownFields()
 
 	var s stepReq
-	if err := decoder.Decode(&s); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := decoder.Decode(&s); err != nil && !(err == io.EOF && sess.Input != nil) {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	if err := algo.DeserializeState(sess.State); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidState,
+			fmt.Sprintf("Failed to deserialize state: %s", err.Error()))
+		return
+	}
+
+	if rejectDone(w, algo) {
 		return
 	}
 
-	if s.Data == 0x0 {
+	if sess.Input != nil {
+		if s.Data != 0x0 {
+			writeError(w, http.StatusUnprocessableEntity, codeInvalidInput,
+				"The input was provided at init, 'byte' must not be sent")
+			return
+		}
+
+		var more bool
+		if s.Data, more = algoexplore.NextInput(algo, sess.Input, sess.History.Pos); !more {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	} else if s.Data == 0x0 {
 		// You could argue that 0x0 is a legitimate state, however in ascii it is NUL
 		// Hence it's unlikely to be a legit input, however this is a default input if the
 		// Client doesn't have a valid one, so we should return
 		glog.Errorln("no data provided")
-		http.Error(w, "No data provided, no state to update", http.StatusNoContent)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	state, err := stepState(algo, s.Data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
+		return
+	}
+	sess.History.Record(s.Data, state)
+	glog.Infof("state: %#v\n", state)
+
+	sess.State = state
+	if err := saveSession(w, r, cookie, sess); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	writeState(w, http.StatusOK, algo, sess)
+}
+
+// stepState steps the algorithm with d, and returns its state after the step
+func stepState(algo algoexplore.AlgoPlugin, d byte) (string, error) {
+	if err := algo.Step(d); err != nil {
+		return "", err
+	}
+	return algo.SerializeState()
+}
+
+type stepsReq struct {
+	// Data accepts either a JSON array of byte values or a base64 string
+	// it must be omitted if the input was provided at init
+	Data   []byte `json:"data"`
+	Count  int    `json:"count"`
+	States bool   `json:"states"`
+}
+
+type stepsResp struct {
+	stateResp
+	Count  int      `json:"count"`
+	States []string `json:"states,omitempty"`
+}
+
+// StepsAlgo advances the algorithm by up to maxBatchSteps bytes in one request
+// returning the final state, and optionally every intermediate state in order.
+// If the input was provided at init, up to 'count' bytes are taken from it
+func StepsAlgo(w http.ResponseWriter, r *http.Request) {
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
+		return
+	}
+
+	cookie, sess, ok := validateSession(w, r, algo)
+	if !ok {
+		return
+	}
+
+	var s stepsReq
+	var body io.Reader = r.Body
+	if err := algoexplore.StrictUnmarshalJSON(&body, &s); err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	if sess.Input != nil && s.Data != nil {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidInput,
+			"The input was provided at init, 'data' must not be sent")
+		return
+	}
+
+	if s.Count == 0 && sess.Input == nil {
+		s.Count = len(s.Data)
+	}
+
+	if s.Count < 1 || (sess.Input == nil && s.Count > len(s.Data)) {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidInput, "Invalid 'count' for the 'data' provided")
+		return
+	}
+
+	if s.Count > maxBatchSteps {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidInput,
+			fmt.Sprintf("'count' exceeds the maximum of %d steps", maxBatchSteps))
+		return
+	}
+
+	if err := algo.DeserializeState(sess.State); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidState,
+			fmt.Sprintf("Failed to deserialize state: %s", err.Error()))
 		return
 	}
 
-	state := session.Values[algoState].(string)
+	if rejectDone(w, algo) {
+		return
+	}
 
-	// https://golang.org/doc/effective_go.html#type_switch
-	switch algo := (*algoInfo).(type) {
-	case *ctph.Ctph:
-		if err := algo.DeserializeState(state); err != nil {
-			glog.Fatalf("Failed to deserialize state: %s", err.Error())
+	var resp stepsResp
+	if s.States {
+		resp.States = make([]string, 0, s.Count)
+	}
+
+	state := sess.State
+	for ; resp.Count < s.Count && !algo.Done(); resp.Count++ {
+		var d byte
+		if sess.Input == nil {
+			d = s.Data[resp.Count]
+		} else if next, more := algoexplore.NextInput(algo, sess.Input, sess.History.Pos); more {
+			d = next
+		} else {
+			break
+		}
+
+		var err error
+		if state, err = stepState(algo, d); err != nil {
+			writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
+			return
+		}
+		sess.History.Record(d, state)
+		if s.States {
+			resp.States = append(resp.States, state)
 		}
-		algo.Step(s.Data)
-		state = algo.SerializeState()
-	default:
-		glog.Fatal("Unable to concretize algo type")
 	}
-	glog.Infof("state: %#v\n", state)
 
-	session.Values[algoState] = state
-	err = session.Save(r, w)
+	sess.State = state
+	st, err := newStateResp(algo, sess)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
+		return
+	}
+	resp.stateResp = st
+
+	if err := saveSession(w, r, cookie, sess); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(state); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Errorf("failed to write response: %s\n", err.Error())
 	}
 }
+
+// BackAlgo rewinds the algorithm by one step
+func BackAlgo(w http.ResponseWriter, r *http.Request) {
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
+		return
+	}
+
+	cookie, sess, ok := validateSession(w, r, algo)
+	if !ok {
+		return
+	}
+
+	seekTo(w, r, algo, cookie, sess, sess.History.Pos-1)
+}
+
+type seekReq struct {
+	Index int `json:"index"`
+}
+
+// SeekAlgo moves the algorithm to the state after 'index' steps, which may be
+// anywhere between the initial state and the furthest step taken so far
+func SeekAlgo(w http.ResponseWriter, r *http.Request) {
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
+		return
+	}
+
+	cookie, sess, ok := validateSession(w, r, algo)
+	if !ok {
+		return
+	}
+
+	var s seekReq
+	var body io.Reader = r.Body
+	if err := algoexplore.StrictUnmarshalJSON(&body, &s); err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	seekTo(w, r, algo, cookie, sess, s.Index)
+}
+
+func seekTo(w http.ResponseWriter, r *http.Request, algo algoexplore.AlgoPlugin,
+	cookie *sessions.Session, sess *algoSession, index int) {
+	if index < 0 || index > len(sess.History.Input) {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidInput,
+			fmt.Sprintf("Invalid 'index', must be between 0 and %d", len(sess.History.Input)))
+		return
+	}
+
+	state, err := sess.History.Seek(algo, index)
+	if err == nil {
+		err = algo.DeserializeState(state)
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidState,
+			fmt.Sprintf("Failed to replay state: %s", err.Error()))
+		return
+	}
+	glog.Infof("state: %#v\n", state)
+
+	sess.State = state
+	if err := saveSession(w, r, cookie, sess); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	writeState(w, http.StatusOK, algo, sess)
+}
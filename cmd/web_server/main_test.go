@@ -2,13 +2,21 @@ package main
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/joekir/algoexplore"
+	"github.com/joekir/algoexplore/internal/session"
 )
 
 func TestInit_withValidLengthField_ReturnsSerializedFHStruct(t *testing.T) {
@@ -35,7 +43,7 @@ func TestInit_withValidLengthField_ReturnsSerializedFHStruct(t *testing.T) {
 	t.Logf("%#v\n", rr.Body.String())
 }
 
-func TestStepAlgo_noSession_Returns500(t *testing.T) {
+func TestStepAlgo_noSession_Returns428(t *testing.T) {
 	var jsonStr = []byte(`{"byte": 103}`)
 	req, err := http.NewRequest("POST", "/ctph/step", bytes.NewBuffer(jsonStr))
 	if err != nil {
@@ -110,3 +118,489 @@ func TestStepAlgo_withSession_StepsItByOne(t *testing.T) {
 		t.Fatalf("expected `window[103,...`, got %s", jsonStr)
 	}
 }
+
+func initCtphSession(t *testing.T, dataLength int) *http.Cookie {
+	t.Helper()
+
+	jsonStr := []byte(fmt.Sprintf(`{"data_length": %d}`, dataLength))
+	req, err := http.NewRequest("POST", "/ctph/init", bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/{algo}/init", Init)
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("init returned wrong status code: got %v want %v\n",
+			status, http.StatusCreated)
+	}
+
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			return c
+		}
+	}
+
+	t.Fatal("init did not return a cookie")
+	return nil
+}
+
+func TestStepsAlgo_withStatesRequested_ReturnsEveryIntermediateState(t *testing.T) {
+	cookie := initCtphSession(t, 10)
+
+	jsonStr := []byte(`{"data": [103, 104, 105], "states": true}`)
+	req, err := http.NewRequest("POST", "/ctph/steps", bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(cookie)
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/{algo}/steps", StepsAlgo)
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v\n",
+			status, http.StatusOK)
+	}
+
+	var resp stepsResp
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+
+	if resp.Count != 3 || len(resp.States) != 3 {
+		t.Fatalf("expected 3 states, got count %d and %d states", resp.Count, len(resp.States))
+	}
+
+	if !strings.Contains(resp.States[0], `"window":[103,0`) {
+		t.Fatalf("expected first state window `[103,0,...`, got %s", resp.States[0])
+	}
+
+	if resp.State != resp.States[2] || !strings.Contains(resp.State, `"window":[103,104,105`) {
+		t.Fatalf("expected final state to match last intermediate state, got %s", resp.State)
+	}
+}
+
+func TestStepsAlgo_withBase64DataAndCount_ReturnsOnlyFinalState(t *testing.T) {
+	cookie := initCtphSession(t, 10)
+
+	// "ghi" base64 encoded, only the first two bytes are stepped
+	jsonStr := []byte(`{"data": "Z2hp", "count": 2}`)
+	req, err := http.NewRequest("POST", "/ctph/steps", bytes.NewBuffer(jsonStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(cookie)
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/{algo}/steps", StepsAlgo)
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v\n",
+			status, http.StatusOK)
+	}
+
+	var resp stepsResp
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+
+	if len(resp.States) != 0 {
+		t.Fatalf("expected no intermediate states, got %d", len(resp.States))
+	}
+
+	if !strings.Contains(resp.State, `"window":[103,104,0`) {
+		t.Fatalf("expected window `[103,104,0,...`, got %s", resp.State)
+	}
+}
+
+func TestStepsAlgo_pastTheInputLength_StopsOnceDone(t *testing.T) {
+	rr := serve(t, "POST", "/rollinghash/init", `{"data_length": 3}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("init returned wrong status code: got %v want %v\n", rr.Code, http.StatusCreated)
+	}
+	cookie := rr.Result().Cookies()[0]
+
+	var init initResp
+	if err := json.NewDecoder(rr.Body).Decode(&init); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+	if init.Done || init.Output != "00000000" {
+		t.Fatalf("expected the initial output and not to be done, got %t %s", init.Done, init.Output)
+	}
+
+	rr = serve(t, "POST", "/rollinghash/steps", `{"data": [1, 2, 3, 4]}`, cookie)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("steps returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusOK, rr.Body)
+	}
+
+	var resp stepsResp
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+
+	// x = 6, y = 7*1 + 7*2 - 1 + 7*3 - 3 = 38 and z = 1<<10 ^ 2<<5 ^ 3 = 1091
+	if resp.Count != 3 || !resp.Done || resp.Output != fmt.Sprintf("%08x", 6+38+1091) {
+		t.Fatalf("expected to be done after 3 steps with the rolling hash, got %d %t %s", resp.Count, resp.Done, resp.Output)
+	}
+
+	rr = serve(t, "POST", "/rollinghash/step", `{"byte": 5}`, cookie)
+	expectError(t, rr, http.StatusConflict, codeAlgoDone)
+}
+
+func TestStepsAlgo_overTheCap_Returns422(t *testing.T) {
+	cookie := initCtphSession(t, 10)
+
+	data, err := json.Marshal(stepsReq{Data: make([]byte, maxBatchSteps+1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("POST", "/ctph/steps", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(cookie)
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/{algo}/steps", StepsAlgo)
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnprocessableEntity {
+		t.Errorf("handler returned wrong status code: got %v want %v\n",
+			status, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestInit_withFileSessionStore_KeepsStateOutOfTheCookie(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileStore, err := session.NewFileStore(dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(s session.Store) { stateStore = s }(stateStore)
+	stateStore = fileStore
+
+	cookie := initCtphSession(t, 10)
+	if strings.Contains(cookie.Value, "window") || len(cookie.Value) > 256 {
+		t.Fatalf("expected an opaque session id cookie, got %s", cookie.Value)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("expected 1 session file, got %d", len(files))
+	}
+}
+
+// fakeSum - a trivial plugin that is only registered for the tests, to prove
+// that the handlers serve any plugin in the registry
+type fakeSum struct {
+	Sum   int `json:"sum"`
+	Steps int `json:"steps"`
+}
+
+func (f *fakeSum) Name() string            { return "fakesum" }
+func (f *fakeSum) Init(inputLen int) error { f.Sum, f.Steps = 0, 0; return nil }
+func (f *fakeSum) Step(d byte) error       { f.Sum += int(d); f.Steps++; return nil }
+func (f *fakeSum) SerializeState() (string, error) {
+	b, err := json.Marshal(f)
+	return string(b), err
+}
+func (f *fakeSum) Done() bool              { return false }
+func (f *fakeSum) Output() (string, error) { return fmt.Sprint(f.Sum), nil }
+func (f *fakeSum) DeserializeState(state string) error {
+	var r io.Reader = strings.NewReader(state)
+	return algoexplore.StrictUnmarshalJSON(&r, f)
+}
+
+func init() {
+	algoexplore.Register(func() algoexplore.AlgoPlugin { return &fakeSum{} })
+}
+
+func TestRouter_withRegisteredFakePlugin_ServesItEndToEnd(t *testing.T) {
+	router := newRouter()
+
+	req, err := http.NewRequest("POST", "/fakesum/init", bytes.NewBufferString(`{"data_length": 3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("init returned wrong status code: got %v want %v\n", status, http.StatusCreated)
+	}
+	cookies := rr.Result().Cookies()
+
+	req, err = http.NewRequest("POST", "/fakesum/step", bytes.NewBufferString(`{"byte": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("step returned wrong status code: got %v want %v\n", status, http.StatusOK)
+	}
+
+	req, err = http.NewRequest("POST", "/fakesum/steps", bytes.NewBufferString(`{"data": [2, 3]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("steps returned wrong status code: got %v want %v\n", status, http.StatusOK)
+	}
+
+	var resp stepsResp
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+
+	if resp.State != `{"sum":6,"steps":3}` {
+		t.Fatalf("expected the fake plugin to have summed all 3 bytes, got %s", resp.State)
+	}
+
+	// the fake session must not be usable with a different algorithm
+	req, err = http.NewRequest("POST", "/ctph/step", bytes.NewBufferString(`{"byte": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusPreconditionRequired {
+		t.Fatalf("step returned wrong status code: got %v want %v\n", status, http.StatusPreconditionRequired)
+	}
+}
+
+// slowStore - a Store that is slow to load, so that concurrent requests would
+// interleave loading and saving a session if they could
+type slowStore struct {
+	session.Store
+}
+
+func (s slowStore) Get(id string) ([]byte, error) {
+	time.Sleep(time.Millisecond)
+	return s.Store.Get(id)
+}
+
+func TestStepAlgo_concurrentlyOnOneSession_KeepsEveryStep(t *testing.T) {
+	defer func(s session.Store) { stateStore = s }(stateStore)
+	stateStore = slowStore{session.NewMemoryStore(time.Minute)}
+
+	rr := serve(t, "POST", "/fakesum/init", `{"data_length": 100}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("init returned wrong status code: got %v want %v\n", rr.Code, http.StatusCreated)
+	}
+	cookie := rr.Result().Cookies()[0]
+
+	const steps = 50
+	codes := make(chan int, steps)
+	for i := 0; i < steps; i++ {
+		go func() {
+			req := httptest.NewRequest("POST", "/fakesum/step", strings.NewReader(`{"byte": 1}`))
+			req.AddCookie(cookie)
+			rr := httptest.NewRecorder()
+			newRouter().ServeHTTP(rr, req)
+			codes <- rr.Code
+		}()
+	}
+	for i := 0; i < steps; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Fatalf("step returned wrong status code: got %v want %v\n", code, http.StatusOK)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/fakesum/step", nil)
+	req.AddCookie(cookie)
+	_, sess, err := loadSession(req, "fakesum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.History.Pos != steps || sess.State != `{"sum":50,"steps":50}` {
+		t.Fatalf("expected all %d steps to be kept, got %d: %s", steps, sess.History.Pos, sess.State)
+	}
+}
+
+func TestInit_withInput_KeepsItOutOfTheSteppedState(t *testing.T) {
+	input := strings.Repeat("abc", 100)
+	cookie, _ := initWithInput(t, fmt.Sprintf(`{"text": %q}`, input))
+
+	req := httptest.NewRequest("POST", "/ctph/step", nil)
+	req.AddCookie(cookie)
+	c, err := cookieStore.Get(req, sessionCookieName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := c.Values[sessionIDKey].(string)
+
+	state, err := stateStore.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(state, []byte(base64.StdEncoding.EncodeToString([]byte(input)))) {
+		t.Fatalf("expected the input to be kept apart from the state, got %s", state)
+	}
+
+	rr := serve(t, "POST", "/ctph/steps", `{"count": 10}`, cookie)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("steps returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusOK, rr.Body)
+	}
+	_, sess, err := loadSession(req, "ctph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sess.Input) != input || sess.History.Pos != 10 {
+		t.Fatalf("expected the input to be loaded with the stepped session, got %d bytes at %d", len(sess.Input), sess.History.Pos)
+	}
+}
+
+func TestListAlgos_ReturnsRegisteredAlgosWithMetadata(t *testing.T) {
+	req, err := http.NewRequest("GET", "/algos", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v\n", status, http.StatusOK)
+	}
+
+	var infos []algoexplore.AlgoInfo
+	if err := json.NewDecoder(rr.Body).Decode(&infos); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+
+	found := map[string]algoexplore.AlgoInfo{}
+	for _, info := range infos {
+		found[info.Name] = info
+	}
+
+	if found["ctph"].DisplayName != "ssdeep" || len(found["ctph"].Example) < 1 {
+		t.Fatalf("expected ctph metadata, got %#v", found["ctph"])
+	}
+
+	if found["fakesum"].DisplayName != "fakesum" {
+		t.Fatalf("expected the fake plugin to be listed, got %#v", infos)
+	}
+}
+
+func TestSchema_withStateDescriber_ReturnsFieldsInOrder(t *testing.T) {
+	req, err := http.NewRequest("GET", "/ctph/schema", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	newRouter().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v\n", status, http.StatusOK)
+	}
+
+	var schema algoexplore.StateSchema
+	if err := json.NewDecoder(rr.Body).Decode(&schema); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+
+	if len(schema.Fields) < 1 || schema.Fields[0].Kind != algoexplore.KindTrigger {
+		t.Fatalf("expected the ctph triggers first, got %#v", schema.Fields)
+	}
+}
+
+func TestOptions_withOptionsInitializer_ReturnsOptionsInOrder(t *testing.T) {
+	for _, tc := range []struct {
+		url   string
+		names string
+	}{
+		{"/ctph/options", "window_size,block_size_min,signature_length,alphabet"},
+		{"/rollinghash/options", ""},
+	} {
+		rr := serve(t, "GET", tc.url, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s returned wrong status code: got %v want %v\n", tc.url, rr.Code, http.StatusOK)
+		}
+
+		var schema algoexplore.OptionsSchema
+		if err := json.NewDecoder(rr.Body).Decode(&schema); err != nil {
+			t.Fatalf("failed to decode response: %s", err.Error())
+		}
+
+		var names []string
+		for _, o := range schema.Options {
+			names = append(names, o.Name)
+		}
+		if strings.Join(names, ",") != tc.names {
+			t.Fatalf("%s: expected options %s, got %#v", tc.url, tc.names, schema.Options)
+		}
+	}
+}
+
+func TestBackAndSeek_withSteppedSession_RewindsAndReplays(t *testing.T) {
+	cookie := initCtphSession(t, 10)
+
+	rr := serve(t, "POST", "/ctph/steps", `{"data": [103, 104, 105]}`, cookie)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("steps returned wrong status code: got %v want %v\n", rr.Code, http.StatusOK)
+	}
+
+	for _, tc := range []struct {
+		method, body, window string
+	}{
+		{"back", "", `\"window\":[103,104,0`},
+		{"seek", `{"index": 0}`, `\"window\":[0,0,0`},
+		{"seek", `{"index": 3}`, `\"window\":[103,104,105`},
+		{"seek", `{"index": 1}`, `\"window\":[103,0,0`},
+	} {
+		rr := serve(t, "POST", "/ctph/"+tc.method, tc.body, cookie)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s %s returned wrong status code: got %v want %v\n", tc.method, tc.body, rr.Code, http.StatusOK)
+		}
+
+		if body := rr.Body.String(); !strings.Contains(body, tc.window) {
+			t.Fatalf("%s %s: expected %s, got %s", tc.method, tc.body, tc.window, body)
+		}
+	}
+
+	rr = serve(t, "POST", "/ctph/seek", `{"index": 4}`, cookie)
+	expectError(t, rr, http.StatusUnprocessableEntity, codeInvalidInput)
+
+	// stepping after going back continues from the rewound position
+	rr = serve(t, "POST", "/ctph/step", `{"byte": 120}`, cookie)
+	if body := rr.Body.String(); !strings.Contains(body, `\"window\":[103,120,0`) {
+		t.Fatalf("expected window `[103,120,0,...`, got %s", body)
+	}
+}
+
+func TestBackAlgo_atTheStart_Returns422(t *testing.T) {
+	cookie := initCtphSession(t, 10)
+
+	rr := serve(t, "POST", "/ctph/back", "", cookie)
+	expectError(t, rr, http.StatusUnprocessableEntity, codeInvalidInput)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/golang/glog"
+	"github.com/gorilla/sessions"
+	"github.com/joekir/algoexplore"
+	"github.com/joekir/algoexplore/internal/session"
+)
+
+// algoSession - everything the server keeps for a client between requests,
+// the client itself only holds the session ID in its cookie
+type algoSession struct {
+	Algo  string `json:"algo"`
+	State string `json:"state"`
+	// Input is only set if the whole input was provided at init. It is kept
+	// in the inputStore, written once rather than with every step.
+	Input   []byte               `json:"-"`
+	History *algoexplore.History `json:"history"`
+
+	// how the algorithm was initialized, so the run can be traced
+	InputLen int             `json:"input_len"`
+	Options  json.RawMessage `json:"options,omitempty"`
+}
+
+// loadSession fetches the session for the algorithm named in the request
+// session.ErrNotFound is returned if there is no live session for that algorithm
+func loadSession(r *http.Request, algoName string) (*sessions.Session, *algoSession, error) {
+	cookie, err := cookieStore.Get(r, sessionCookieName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id, ok := cookie.Values[sessionIDKey].(string)
+	if cookie.IsNew || !ok {
+		return nil, nil, session.ErrNotFound
+	}
+
+	data, err := stateStore.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var s algoSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, nil, err
+	}
+
+	if s.Algo != algoName {
+		return nil, nil, session.ErrNotFound
+	}
+
+	input, err := inputStore.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(input) > 0 {
+		s.Input = input
+	}
+
+	return cookie, &s, nil
+}
+
+// newSession discards any existing session for the client and starts a new one
+func newSession(r *http.Request) (*sessions.Session, error) {
+	// A session is always returned
+	cookie, _ := cookieStore.Get(r, sessionCookieName)
+
+	if id, ok := cookie.Values[sessionIDKey].(string); ok {
+		glog.Infoln("Deleting old session")
+		if err := stateStore.Delete(id); err != nil {
+			return nil, err
+		}
+		if err := inputStore.Delete(id); err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := session.NewID()
+	if err != nil {
+		return nil, err
+	}
+	cookie.Values[sessionIDKey] = id
+
+	return cookie, nil
+}
+
+// saveInput persists the input of a new session, which saveSession then
+// keeps alive without rewriting it
+func saveInput(cookie *sessions.Session, s *algoSession) error {
+	return inputStore.Set(cookie.Values[sessionIDKey].(string), s.Input)
+}
+
+// saveSession persists the session state server-side and refreshes the cookie
+func saveSession(w http.ResponseWriter, r *http.Request, cookie *sessions.Session, s *algoSession) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	id := cookie.Values[sessionIDKey].(string)
+	if err := stateStore.Set(id, data); err != nil {
+		return err
+	}
+	if err := inputStore.Touch(id); err != nil {
+		return err
+	}
+
+	return cookie.Save(r, w)
+}
+
+// lockSession holds the client's session, if it has one, while next serves
+// the request, so that concurrent requests on one cookie can't interleave
+// loading, modifying then saving it and lose an update
+func lockSession(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := cookieStore.Get(r, sessionCookieName)
+		if err == nil {
+			if id, ok := cookie.Values[sessionIDKey].(string); ok {
+				defer stateStore.Lock(id)()
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
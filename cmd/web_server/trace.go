@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+	"github.com/joekir/algoexplore"
+)
+
+// defaultMaxTraceBytes caps the size of a trace that may be loaded, unless
+// overridden by the max_trace_bytes flag. It leaves room for the states.
+const defaultMaxTraceBytes = 64 << 20
+
+// maxTraceStepsPerByte bounds the steps of a loaded trace by its input length,
+// leaving room for the passes of an algorithm that retries its input
+const maxTraceStepsPerByte = 32
+
+type traceResp struct {
+	initResp
+	Count int `json:"count"`
+}
+
+// Trace downloads the session's run up to its current position as an
+// algoexplore.Trace, with the state after every step if ?states=true
+func Trace(w http.ResponseWriter, r *http.Request) {
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
+		return
+	}
+
+	_, sess, ok := validateSession(w, r, algo)
+	if !ok {
+		return
+	}
+
+	states := false
+	if v := r.URL.Query().Get("states"); len(v) > 0 {
+		var err error
+		if states, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, "Invalid 'states', must be true or false")
+			return
+		}
+	}
+
+	steps := sess.History.Input[:sess.History.Pos]
+	trace, err := algoexplore.RecordTrace(algo, sess.InputLen, sess.Input, sess.Options, steps, states)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", algo.Name()+".trace.json"))
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(trace); err != nil {
+		glog.Errorf("failed to write response: %s\n", err.Error())
+	}
+}
+
+// LoadTrace replays an algoexplore.Trace, verifying every state it recorded,
+// and starts a session at the end of the run that can be stepped and seeked
+// through like any other
+func LoadTrace(w http.ResponseWriter, r *http.Request) {
+	algo, ok := validateAlgo(w, mux.Vars(r))
+	if !ok {
+		return
+	}
+
+	var trace algoexplore.Trace
+	if err := readJSONBody(w, r, *maxTraceBytes, &trace); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+
+	if int64(trace.InputLength) > *maxInputBytes || int64(len(trace.Input)) > *maxInputBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, codeInvalidInput,
+			fmt.Sprintf("The input exceeds the maximum of %d bytes", *maxInputBytes))
+		return
+	}
+	if maxSteps := maxTraceStepsPerByte * (trace.InputLength + 1); len(trace.Steps) > maxSteps {
+		writeError(w, http.StatusRequestEntityTooLarge, codeInvalidInput,
+			fmt.Sprintf("The trace has %d steps, over the maximum of %d for its input", len(trace.Steps), maxSteps))
+		return
+	}
+
+	sess := &algoSession{
+		Algo:     algo.Name(),
+		Input:    trace.Input,
+		InputLen: trace.InputLength,
+		Options:  trace.Options,
+	}
+	// the replayed states go straight into the bounded history
+	err := trace.Replay(algo, func(step int, state string) error {
+		if step == 0 {
+			sess.History = algoexplore.NewHistory(historySize, historyCheckpointEvery, state)
+		} else {
+			sess.History.Record(trace.Steps[step-1], state)
+		}
+		sess.State = state
+		return nil
+	})
+	switch err.(type) {
+	case nil:
+	case *algoexplore.PanicError:
+		writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
+		return
+	case *algoexplore.TraceMismatchError:
+		writeError(w, http.StatusUnprocessableEntity, codeTraceMismatch, err.Error())
+		return
+	default:
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidInput, "Failed to replay: "+err.Error())
+		return
+	}
+
+	if !replaceSession(w, r, sess) {
+		return
+	}
+
+	st, err := newStateResp(algo, sess)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeAlgoFailed, err.Error())
+		return
+	}
+
+	resp := traceResp{initResp{st, trace.Input}, len(trace.Steps)}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Errorf("failed to write response: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/joekir/algoexplore"
+)
+
+// recordCtphTrace steps ctph over text with options and downloads its trace
+func recordCtphTrace(t *testing.T, steps int, states string) algoexplore.Trace {
+	t.Helper()
+
+	rr := serve(t, "POST", "/ctph/init", `{"text": "The quick brown fox jumped over the lazy dog's back", `+
+		`"options": {"block_size_min": 2}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("init returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusCreated, rr.Body)
+	}
+	cookie := rr.Result().Cookies()[0]
+
+	rr = serve(t, "POST", "/ctph/steps", `{"count": `+strconv.Itoa(steps)+`}`, cookie)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("steps returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusOK, rr.Body)
+	}
+
+	rr = serve(t, "GET", "/ctph/trace?states="+states, "", cookie)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("trace returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusOK, rr.Body)
+	}
+	if disposition := rr.Header().Get("Content-Disposition"); disposition != `attachment; filename="ctph.trace.json"` {
+		t.Fatalf("expected the trace to be downloaded, got %s", disposition)
+	}
+
+	var trace algoexplore.Trace
+	if err := json.NewDecoder(rr.Body).Decode(&trace); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+	return trace
+}
+
+func postTrace(t *testing.T, algo string, trace algoexplore.Trace) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serve(t, "POST", "/"+algo+"/trace", string(body))
+}
+
+func TestTrace_withStates_RecordsTheRunSoFar(t *testing.T) {
+	trace := recordCtphTrace(t, 10, "true")
+
+	if trace.Algo != "ctph" || trace.Version != "1" || trace.InputLength != 51 || len(trace.Input) != 51 {
+		t.Fatalf("unexpected trace: %+v", trace)
+	}
+	if string(trace.Options) != `{"block_size_min":2}` {
+		t.Fatalf("expected the init options, got %s", trace.Options)
+	}
+	if len(trace.Steps) != 10 || len(trace.States) != 11 {
+		t.Fatalf("expected 10 steps and 11 states, got %d and %d", len(trace.Steps), len(trace.States))
+	}
+
+	if trace = recordCtphTrace(t, 10, "false"); len(trace.Steps) != 10 || trace.States != nil {
+		t.Fatalf("expected the steps without states, got %d steps and %d states", len(trace.Steps), len(trace.States))
+	}
+}
+
+func TestLoadTrace_withRecordedTrace_ResumesTheSession(t *testing.T) {
+	trace := recordCtphTrace(t, 10, "true")
+
+	rr := postTrace(t, "ctph", trace)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("load returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusCreated, rr.Body)
+	}
+	cookie := rr.Result().Cookies()[0]
+
+	var resp traceResp
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+	if resp.Count != 10 || resp.Cursor != 10 || resp.State != trace.States[10] || len(resp.Input) != 51 {
+		t.Fatalf("expected to resume after 10 steps, got %+v", resp)
+	}
+
+	// the loaded session can be stepped back through, and on
+	rr = serve(t, "POST", "/ctph/seek", `{"index": 4}`, cookie)
+	var seeked stateResp
+	if err := json.NewDecoder(rr.Body).Decode(&seeked); err != nil {
+		t.Fatalf("failed to decode response: %s", err.Error())
+	}
+	if rr.Code != http.StatusOK || seeked.State != trace.States[4] {
+		t.Fatalf("expected the recorded state after 4 steps, got %v %s", rr.Code, seeked.State)
+	}
+
+	rr = serve(t, "POST", "/ctph/step", `{}`, cookie)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("step returned wrong status code: got %v want %v: %s\n", rr.Code, http.StatusOK, rr.Body)
+	}
+}
+
+func TestLoadTrace_withTamperedState_Returns422(t *testing.T) {
+	trace := recordCtphTrace(t, 10, "true")
+	trace.States[7] = trace.States[6]
+
+	expectError(t, postTrace(t, "ctph", trace), http.StatusUnprocessableEntity, codeTraceMismatch)
+}
+
+func TestLoadTrace_withInvalidTraces_Returns422(t *testing.T) {
+	trace := recordCtphTrace(t, 10, "false")
+	expectError(t, postTrace(t, "ctph-stream", trace), http.StatusUnprocessableEntity, codeInvalidInput)
+
+	trace.Version = "0"
+	expectError(t, postTrace(t, "ctph", trace), http.StatusUnprocessableEntity, codeInvalidInput)
+}
+
+func TestLoadTrace_withStepsOtherThanTheInput_Returns422(t *testing.T) {
+	for _, steps := range []string{"abd\x00", "abc\x00aaaaa"} {
+		trace := algoexplore.Trace{Format: algoexplore.TraceFormat, Algo: "ctph", Version: "1", InputLength: 3,
+			Input: []byte("abc"), Steps: []byte(steps)}
+		expectError(t, postTrace(t, "ctph", trace), http.StatusUnprocessableEntity, codeInvalidInput)
+	}
+}
+
+func TestLoadTrace_withStepsOnceDone_Returns422(t *testing.T) {
+	trace := algoexplore.Trace{Format: algoexplore.TraceFormat, Algo: "rollinghash", Version: "1", InputLength: 2,
+		Steps: []byte("abcdef")}
+	expectError(t, postTrace(t, "rollinghash", trace), http.StatusUnprocessableEntity, codeInvalidInput)
+}
+
+func TestLoadTrace_overTheLimits_Returns413(t *testing.T) {
+	for _, trace := range map[string]algoexplore.Trace{
+		"input length": {Format: algoexplore.TraceFormat, Algo: "ctph", Version: "1",
+			InputLength: defaultMaxInputBytes + 1},
+		"steps": {Format: algoexplore.TraceFormat, Algo: "rollinghash", Version: "1", InputLength: 2,
+			Steps: make([]byte, 3*maxTraceStepsPerByte+1)},
+	} {
+		expectError(t, postTrace(t, trace.Algo, trace), http.StatusRequestEntityTooLarge, codeInvalidInput)
+	}
+}
+
+func TestLoadTrace_withMalformedBody_Returns400(t *testing.T) {
+	expectError(t, serve(t, "POST", "/ctph/trace", `{"algo": "ctph", "nope": 1}`), http.StatusBadRequest, codeBadRequest)
+}
+
+func TestTrace_withInvalidStates_Returns400(t *testing.T) {
+	cookie := initCtphSession(t, 3)
+	expectError(t, serve(t, "GET", "/ctph/trace?states=maybe", "", cookie), http.StatusBadRequest, codeBadRequest)
+}
+
+func TestTrace_withoutSession_Returns428(t *testing.T) {
+	expectError(t, serve(t, "GET", "/ctph/trace", ""), http.StatusPreconditionRequired, codeNoSession)
+}
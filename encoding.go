@@ -0,0 +1,52 @@
+package algoexplore
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Encodings supported by EncodeInput
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingLatin1  = "latin-1"
+	EncodingUTF16LE = "utf-16le"
+	EncodingHex     = "hex"
+	EncodingBase64  = "base64"
+)
+
+// Encodings lists the names accepted by EncodeInput
+var Encodings = []string{EncodingUTF8, EncodingLatin1, EncodingUTF16LE, EncodingHex, EncodingBase64}
+
+// EncodeInput converts text to the exact bytes an algorithm is stepped with
+// An empty encoding means utf-8
+func EncodeInput(text, encoding string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "", EncodingUTF8:
+		return []byte(text), nil
+	case EncodingLatin1:
+		out := make([]byte, 0, len(text))
+		for i, r := range text {
+			if r > 0xFF {
+				return nil, fmt.Errorf("character %q at offset %d is not representable in latin-1", r, i)
+			}
+			out = append(out, byte(r))
+		}
+		return out, nil
+	case EncodingUTF16LE:
+		units := utf16.Encode([]rune(text))
+		out := make([]byte, 0, 2*len(units))
+		for _, u := range units {
+			out = append(out, byte(u), byte(u>>8))
+		}
+		return out, nil
+	case EncodingHex:
+		return hex.DecodeString(strings.Join(strings.Fields(text), ""))
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	default:
+		return nil, fmt.Errorf("unknown encoding: %s, expected one of %s", encoding, strings.Join(Encodings, ", "))
+	}
+}
@@ -0,0 +1,44 @@
+package algoexplore
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeInput_withEachEncoding_ProducesExactBytes(t *testing.T) {
+	for _, tc := range []struct {
+		text, encoding string
+		expected       []byte
+	}{
+		{"é🦊", "", []byte{0xc3, 0xa9, 0xf0, 0x9f, 0xa6, 0x8a}},
+		{"é🦊", EncodingUTF8, []byte{0xc3, 0xa9, 0xf0, 0x9f, 0xa6, 0x8a}},
+		{"é", EncodingLatin1, []byte{0xe9}},
+		{"aé🦊", EncodingUTF16LE, []byte{0x61, 0x00, 0xe9, 0x00, 0x3e, 0xd8, 0x8a, 0xdd}},
+		{"de ad BE EF", EncodingHex, []byte{0xde, 0xad, 0xbe, 0xef}},
+		{"3q2+7w==", "BASE64", []byte{0xde, 0xad, 0xbe, 0xef}},
+	} {
+		out, err := EncodeInput(tc.text, tc.encoding)
+		if err != nil {
+			t.Fatalf("%s as %s: %v", tc.text, tc.encoding, err)
+		}
+
+		if !bytes.Equal(out, tc.expected) {
+			t.Fatalf("%s as %s: expected %x, got %x", tc.text, tc.encoding, tc.expected, out)
+		}
+	}
+}
+
+func TestEncodeInput_withUnrepresentableInput_ReturnsError(t *testing.T) {
+	for _, tc := range []struct {
+		text, encoding string
+	}{
+		{"🦊", EncodingLatin1},
+		{"abc", EncodingHex},
+		{"!!", EncodingBase64},
+		{"abc", "ebcdic"},
+	} {
+		if _, err := EncodeInput(tc.text, tc.encoding); err == nil {
+			t.Fatalf("expected an error encoding %s as %s", tc.text, tc.encoding)
+		}
+	}
+}
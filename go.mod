@@ -3,8 +3,6 @@ module github.com/joekir/algoexplore
 go 1.15
 
 require (
-	github.com/agnivade/levenshtein v1.1.1
-	github.com/dgryski/trifles v0.0.0-20211222222045-8cbc5c9974ec // indirect
 	github.com/golang/glog v1.0.0
 	github.com/google/go-cmp v0.5.7
 	github.com/gorilla/mux v1.8.0
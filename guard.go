@@ -0,0 +1,93 @@
+package algoexplore
+
+import "fmt"
+
+// PanicError - a panic recovered from a plugin method, returned in its place
+// so that a misbehaving plugin fails one session rather than the server
+type PanicError struct {
+	Algo  string
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("algo %s panicked: %v", e.Algo, e.Value)
+}
+
+// protect calls f, returning any panic in it as a *PanicError
+func protect(name string, f func() error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &PanicError{Algo: name, Value: p}
+		}
+	}()
+
+	return f()
+}
+
+// guarded - a registered plugin, every method of which recovers from panics
+type guarded struct {
+	plugin AlgoPlugin
+}
+
+func guard(algo AlgoPlugin) AlgoPlugin {
+	return &guarded{algo}
+}
+
+func (g *guarded) Name() string {
+	return g.plugin.Name()
+}
+
+func (g *guarded) Init(inputLen int) error {
+	return protect(g.Name(), func() error { return g.plugin.Init(inputLen) })
+}
+
+func (g *guarded) Step(d byte) error {
+	return protect(g.Name(), func() error { return g.plugin.Step(d) })
+}
+
+func (g *guarded) SerializeState() (state string, err error) {
+	err = protect(g.Name(), func() error {
+		state, err = g.plugin.SerializeState()
+		return err
+	})
+	return state, err
+}
+
+func (g *guarded) DeserializeState(state string) error {
+	return protect(g.Name(), func() error { return g.plugin.DeserializeState(state) })
+}
+
+// Done reports a plugin that panics when asked as done, so that it is not
+// stepped any further
+func (g *guarded) Done() (done bool) {
+	err := protect(g.Name(), func() error {
+		done = g.plugin.Done()
+		return nil
+	})
+	return done || err != nil
+}
+
+func (g *guarded) Output() (output string, err error) {
+	err = protect(g.Name(), func() error {
+		output, err = g.plugin.Output()
+		return err
+	})
+	return output, err
+}
+
+// Unwrap returns the guarded plugin
+func (g *guarded) Unwrap() interface{} {
+	return g.plugin
+}
+
+// unwrap returns the plugin inside any guard or adapter around algo, which is
+// what implements the optional interfaces, e.g. Describer
+func unwrap(algo interface{}) interface{} {
+	for {
+		w, ok := algo.(interface{ Unwrap() interface{} })
+		if !ok {
+			return algo
+		}
+		algo = w.Unwrap()
+	}
+}
@@ -0,0 +1,43 @@
+package algoexplore
+
+import (
+	"errors"
+	"testing"
+)
+
+// Panicker - a fake that panics whenever it is stepped or serialized
+type Panicker struct{ Fake }
+
+func (p *Panicker) Name() string                    { return "panicker" }
+func (p *Panicker) Step(d byte) error               { panic("step") }
+func (p *Panicker) SerializeState() (string, error) { panic("serialize") }
+func (p *Panicker) Position() int                   { return 7 }
+func (p *Panicker) Done() bool                      { panic("done") }
+
+func TestGetAlgo_withPanickingPlugin_ReturnsPanicErrors(t *testing.T) {
+	Register(func() AlgoPlugin { return &Panicker{} })
+
+	algo, err := GetAlgo("panicker")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p *PanicError
+	if err := algo.Step(1); !errors.As(err, &p) || p.Value != "step" || p.Algo != "panicker" {
+		t.Fatalf("expected the step panic as an error, got %v", err)
+	}
+	if _, err := algo.SerializeState(); !errors.As(err, &p) || p.Value != "serialize" {
+		t.Fatalf("expected the serialize panic as an error, got %v", err)
+	}
+	if err := algo.Init(1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !algo.Done() {
+		t.Fatal("expected a plugin that panics when asked to be done")
+	}
+
+	// the optional interfaces of the plugin are still found through the guard
+	if pos, ok := PositionOf(algo); !ok || pos != 7 {
+		t.Fatalf("expected position 7, got %d", pos)
+	}
+}
@@ -0,0 +1,147 @@
+package algoexplore
+
+import (
+	"fmt"
+)
+
+// maxCheckpoints bounds the checkpoints a History keeps, once there would be
+// more every other one is dropped and the interval between them doubled
+const maxCheckpoints = 64
+
+// Snapshot - the serialized state of an algorithm after Pos steps
+type Snapshot struct {
+	Pos   int    `json:"pos"`
+	State string `json:"state"`
+}
+
+// History - a bounded record of the states an algorithm has been through, so
+// that it can be stepped backwards or seeked to any position already reached.
+//
+// The last Size states are kept in a ring, and every CheckpointEvery steps a
+// checkpoint is kept, at most maxCheckpoints of them as the interval grows
+// with the run. Positions outside the ring are recomputed by replaying the
+// recorded input from the nearest earlier checkpoint, which relies on the
+// plugin being deterministic.
+type History struct {
+	Size            int        `json:"size"`
+	CheckpointEvery int        `json:"checkpoint_every"`
+	Pos             int        `json:"pos"`
+	Input           []byte     `json:"input"`
+	Ring            []Snapshot `json:"ring"`
+	Checkpoints     []Snapshot `json:"checkpoints"`
+}
+
+// NewHistory starts a history at position 0 from the state after Init
+func NewHistory(size, checkpointEvery int, initial string) *History {
+	if size < 1 || checkpointEvery < 1 {
+		panic(fmt.Sprintf("invalid history size %d or checkpoint interval %d", size, checkpointEvery))
+	}
+
+	start := Snapshot{Pos: 0, State: initial}
+	return &History{
+		Size:            size,
+		CheckpointEvery: checkpointEvery,
+		Ring:            []Snapshot{start},
+		Checkpoints:     []Snapshot{start},
+	}
+}
+
+// Record notes that d was stepped from the current position resulting in state
+// Anything recorded beyond the current position, e.g. before going back, is discarded
+func (h *History) Record(d byte, state string) {
+	h.Input = append(h.Input[:h.Pos], d)
+	h.Pos++
+
+	h.Ring = truncateAfter(h.Ring, h.Pos-1)
+	h.Checkpoints = truncateAfter(h.Checkpoints, h.Pos-1)
+
+	h.remember(Snapshot{Pos: h.Pos, State: state})
+	if h.Pos%h.CheckpointEvery == 0 {
+		h.Checkpoints = append(h.Checkpoints, Snapshot{Pos: h.Pos, State: state})
+	}
+	if len(h.Checkpoints) > maxCheckpoints {
+		h.thin()
+	}
+}
+
+// thin doubles the checkpoint interval, keeping only the checkpoints that
+// still fall on it, which always includes the one at position 0
+func (h *History) thin() {
+	h.CheckpointEvery *= 2
+
+	kept := h.Checkpoints[:0]
+	for _, s := range h.Checkpoints {
+		if s.Pos%h.CheckpointEvery == 0 {
+			kept = append(kept, s)
+		}
+	}
+	h.Checkpoints = kept
+}
+
+// Back moves one step backwards, see Seek
+func (h *History) Back(algo AlgoPlugin) (string, error) {
+	return h.Seek(algo, h.Pos-1)
+}
+
+// Seek moves to position n, i.e. the state after n steps, and returns that state
+// algo is used to replay from the nearest checkpoint when n is not in the ring
+func (h *History) Seek(algo AlgoPlugin, n int) (string, error) {
+	if n < 0 || n > len(h.Input) {
+		return "", fmt.Errorf("position %d is outside the recorded range 0-%d", n, len(h.Input))
+	}
+
+	base := h.Checkpoints[0]
+	for _, snapshots := range [][]Snapshot{h.Checkpoints, h.Ring} {
+		for _, s := range snapshots {
+			if s.Pos <= n && s.Pos > base.Pos {
+				base = s
+			}
+		}
+	}
+
+	state := base.State
+	if base.Pos < n {
+		if err := algo.DeserializeState(base.State); err != nil {
+			return "", err
+		}
+
+		for _, d := range h.Input[base.Pos:n] {
+			if err := algo.Step(d); err != nil {
+				return "", err
+			}
+		}
+
+		var err error
+		if state, err = algo.SerializeState(); err != nil {
+			return "", err
+		}
+		h.remember(Snapshot{Pos: n, State: state})
+	}
+
+	h.Pos = n
+	return state, nil
+}
+
+// remember adds the snapshot to the ring, evicting the oldest once it is full
+func (h *History) remember(s Snapshot) {
+	for _, r := range h.Ring {
+		if r.Pos == s.Pos {
+			return
+		}
+	}
+
+	h.Ring = append(h.Ring, s)
+	if len(h.Ring) > h.Size {
+		h.Ring = h.Ring[len(h.Ring)-h.Size:]
+	}
+}
+
+func truncateAfter(snapshots []Snapshot, pos int) []Snapshot {
+	kept := snapshots[:0]
+	for _, s := range snapshots {
+		if s.Pos <= pos {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,155 @@
+package algoexplore
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Summer - a deterministic fake whose state is the running sum of its input
+type Summer struct {
+	sum, steps int
+}
+
+func (s *Summer) Name() string            { return "summer" }
+func (s *Summer) Init(inputLen int) error { s.sum, s.steps = 0, 0; return nil }
+func (s *Summer) Step(d byte) error       { s.sum += int(d); s.steps++; return nil }
+func (s *Summer) SerializeState() (string, error) {
+	return fmt.Sprintf("%d/%d", s.sum, s.steps), nil
+}
+func (s *Summer) Done() bool              { return false }
+func (s *Summer) Output() (string, error) { return fmt.Sprint(s.sum), nil }
+func (s *Summer) DeserializeState(state string) error {
+	_, err := fmt.Sscanf(state, "%d/%d", &s.sum, &s.steps)
+	return err
+}
+
+func recordAll(algo AlgoPlugin, h *History, data []byte) {
+	for _, d := range data {
+		algo.Step(d)
+		h.Record(d, serialized(algo))
+	}
+}
+
+// serialized returns the state of a fake, which never fails to serialize
+func serialized(algo AlgoPlugin) string {
+	state, _ := algo.SerializeState()
+	return state
+}
+
+func TestHistory_Back_ReturnsPreviousStates(t *testing.T) {
+	algo := &Summer{}
+	h := NewHistory(4, 100, serialized(algo))
+	recordAll(algo, h, []byte{1, 2, 3})
+
+	for _, expected := range []string{"3/2", "1/1", "0/0"} {
+		state, err := h.Back(&Summer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if state != expected {
+			t.Fatalf("expected %s, got %s", expected, state)
+		}
+	}
+
+	if _, err := h.Back(&Summer{}); err == nil {
+		t.Fatal("expected an error going back past the start")
+	}
+}
+
+func TestHistory_Seek_outsideRing_ReplaysFromNearestCheckpoint(t *testing.T) {
+	algo := &Summer{}
+	h := NewHistory(2, 3, serialized(algo))
+	recordAll(algo, h, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+
+	if len(h.Ring) != 2 || len(h.Checkpoints) != 4 {
+		t.Fatalf("expected a ring of 2 and 4 checkpoints, got %d and %d", len(h.Ring), len(h.Checkpoints))
+	}
+
+	for _, tc := range []struct {
+		n        int
+		expected string
+	}{
+		{5, "15/5"},
+		{1, "1/1"},
+		{10, "55/10"},
+		{0, "0/0"},
+	} {
+		state, err := h.Seek(&Summer{}, tc.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if state != tc.expected || h.Pos != tc.n {
+			t.Fatalf("seek(%d): expected %s, got %s at %d", tc.n, tc.expected, state, h.Pos)
+		}
+	}
+}
+
+func TestHistory_Record_pastMaxCheckpoints_ThinsThem(t *testing.T) {
+	algo := &Summer{}
+	h := NewHistory(2, 1, serialized(algo))
+	data := make([]byte, 10*maxCheckpoints)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	recordAll(algo, h, data)
+
+	if len(h.Checkpoints) > maxCheckpoints {
+		t.Fatalf("expected at most %d checkpoints, got %d", maxCheckpoints, len(h.Checkpoints))
+	}
+	if h.CheckpointEvery != 16 {
+		t.Fatalf("expected the checkpoint interval to double to 16, got %d", h.CheckpointEvery)
+	}
+	for _, s := range h.Checkpoints {
+		if s.Pos%h.CheckpointEvery != 0 {
+			t.Fatalf("checkpoint at %d is off the interval of %d", s.Pos, h.CheckpointEvery)
+		}
+	}
+
+	for _, n := range []int{0, 1, 17, 333, len(data) - 5} {
+		expected := &Summer{}
+		for _, d := range data[:n] {
+			expected.Step(d)
+		}
+		state, err := h.Seek(&Summer{}, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state != serialized(expected) {
+			t.Fatalf("seek %d: expected %s, got %s", n, serialized(expected), state)
+		}
+	}
+}
+
+func TestHistory_Record_afterBack_DiscardsTheFuture(t *testing.T) {
+	algo := &Summer{}
+	h := NewHistory(8, 2, serialized(algo))
+	recordAll(algo, h, []byte{1, 2, 3, 4})
+
+	if _, err := h.Seek(algo, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := algo.DeserializeState("3/2"); err != nil {
+		t.Fatal(err)
+	}
+	recordAll(algo, h, []byte{10})
+
+	if len(h.Input) != 3 {
+		t.Fatalf("expected the input to be truncated to 3 bytes, got %v", h.Input)
+	}
+
+	if _, err := h.Seek(&Summer{}, 4); err == nil {
+		t.Fatal("expected an error seeking into the discarded future")
+	}
+
+	state, err := h.Seek(&Summer{}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state != "13/3" {
+		t.Fatalf("expected 13/3, got %s", state)
+	}
+}
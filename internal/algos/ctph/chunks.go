@@ -0,0 +1,92 @@
+package ctph
+
+import "fmt"
+
+// Chunk - the input bytes [Start, End) that were digested into one character
+// of a signature
+type Chunk struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Chunks - the input chunk behind each character of both signature parts
+type Chunks struct {
+	Sig1 []Chunk `json:"sig1"`
+	Sig2 []Chunk `json:"sig2"`
+}
+
+// HashChunks hashes data the same way as Hash, also recording where each
+// signature character's piece of the input starts and ends
+func HashChunks(data []byte) (string, Chunks, error) {
+	if len(data) == 0 {
+		return fmt.Sprintf("%d::", blockSizeMin), Chunks{Sig1: []Chunk{}, Sig2: []Chunk{}}, nil
+	}
+
+	var chunks Chunks
+	var c1, c2 chunker
+
+	ctph := new(Ctph)
+	if err := ctph.Init(len(data)); err != nil {
+		return "", Chunks{}, err
+	}
+	for ctph.Retry {
+		i := ctph.Position()
+		if i == 0 {
+			// a retry starts over at a smaller block size
+			chunks = Chunks{Sig1: []Chunk{}, Sig2: []Chunk{}}
+			c1, c2 = chunker{}, chunker{}
+		}
+
+		if i == len(data) {
+			digested := ctph.Rh.sum() != 0
+			n1, n2 := len(ctph.Sig1), len(ctph.Sig2)
+			if err := ctph.Step(0); err != nil {
+				return "", Chunks{}, err
+			}
+			if !ctph.Retry {
+				chunks.Sig1 = c1.last(chunks.Sig1, len(ctph.Sig1) > n1, digested, i)
+				chunks.Sig2 = c2.last(chunks.Sig2, len(ctph.Sig2) > n2, digested, i)
+			}
+			continue
+		}
+
+		n1, n2 := len(ctph.Sig1), len(ctph.Sig2)
+		if err := ctph.Step(data[i]); err != nil {
+			return "", Chunks{}, err
+		}
+		chunks.Sig1 = c1.trigger(chunks.Sig1, ctph.IsTrigger1, len(ctph.Sig1) > n1, i+1)
+		chunks.Sig2 = c2.trigger(chunks.Sig2, ctph.IsTrigger2, len(ctph.Sig2) > n2, i+1)
+	}
+
+	return ctph.printSSDeep(), chunks, nil
+}
+
+// chunker follows the pieces of one signature part, once the part is full a
+// trigger no longer starts a new piece, it only moves where the tail ends
+type chunker struct {
+	start   int
+	tailEnd int
+}
+
+func (c *chunker) trigger(chunks []Chunk, triggered, appended bool, end int) []Chunk {
+	switch {
+	case appended:
+		chunks = append(chunks, Chunk{c.start, end})
+		c.start = end
+	case triggered:
+		c.tailEnd = end
+	}
+	return chunks
+}
+
+// last records the final character, which digests the rest of the input,
+// or repeats the tail from the last trigger if the rolling hash ended at zero
+func (c *chunker) last(chunks []Chunk, appended, digested bool, end int) []Chunk {
+	if !appended {
+		return chunks
+	}
+	if !digested {
+		end = c.tailEnd
+	}
+	return append(chunks, Chunk{c.start, end})
+}
@@ -0,0 +1,78 @@
+package ctph
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+// every signature character must be the hash of the chunk recorded for it
+func checkChunks(t *testing.T, data []byte, sig string, chunks []Chunk) {
+	if len(sig) != len(chunks) {
+		t.Fatalf("expected %d chunks for %s, got %d", len(sig), sig, len(chunks))
+	}
+
+	for k, c := range chunks {
+		// only the final character may digest nothing, when the last byte triggered
+		if c.Start < 0 || c.End > len(data) || c.Start > c.End || (c.Start == c.End && k < len(chunks)-1) {
+			t.Fatalf("invalid chunk %d %+v for input of %d bytes", k, c, len(data))
+		}
+		if k > 0 && c.Start != chunks[k-1].End {
+			t.Fatalf("chunk %d %+v does not follow %+v", k, c, chunks[k-1])
+		}
+
+		h := NewFNV()
+		if _, err := h.Write(data[c.Start:c.End]); err != nil {
+			t.Fatal(err)
+		}
+		if b64Chars[h.Sum32()&0x3F] != sig[k] {
+			t.Fatalf("chunk %d %+v does not hash to %c in %s", k, c, sig[k], sig)
+		}
+	}
+}
+
+func TestHashChunks_EveryCharacterHashesItsChunk(t *testing.T) {
+	mobyDick, err := ioutil.ReadFile("testdata/mobydick.txt")
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+	random := make([]byte, 200000)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	for _, data := range [][]byte{
+		mobyDick,
+		random,
+		random[:1000],
+		[]byte("Fuzzy Wuzzy was a bear, Fuzzy Wuzzy had no hair"),
+		bytes.Repeat([]byte{'a'}, 3000),
+		append(bytes.Repeat([]byte("The quick brown fox "), 500), make([]byte, 64)...),
+		// sig2 fills up, then the rolling hash ends at zero
+		append(append([]byte{}, random[:196000]...), make([]byte, 64)...),
+	} {
+		sig, chunks, err := HashChunks(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h := hash(t, data); sig != h {
+			t.Fatalf("expected %s, got %s", h, sig)
+		}
+
+		_, sig1, sig2, ok := parseSignature(sig)
+		if !ok {
+			t.Fatalf("invalid signature %s", sig)
+		}
+		checkChunks(t, data, sig1, chunks.Sig1)
+		checkChunks(t, data, sig2, chunks.Sig2)
+	}
+}
+
+func TestHashChunks_WithEmptyInput_ReturnsNoChunks(t *testing.T) {
+	sig, chunks, err := HashChunks(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig != "3::" || len(chunks.Sig1) != 0 || len(chunks.Sig2) != 0 {
+		t.Fatalf("unexpected %s %+v", sig, chunks)
+	}
+}
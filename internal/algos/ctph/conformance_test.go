@@ -0,0 +1,27 @@
+package ctph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/joekir/algoexplore/algotest"
+)
+
+func TestConformance(t *testing.T) {
+	input := []byte("The quick brown fox jumped over the lazy dog's back")
+
+	for _, name := range []string{"ctph", "ctph-stream", "rollinghash", "fnv32-ssdeep"} {
+		algotest.Run(t, name, algotest.Config{
+			Input:  input,
+			Golden: "testdata/golden/" + name + ".trace.json",
+		})
+	}
+
+	// a small block size exercises several trigger points per pass
+	algotest.Run(t, "ctph", algotest.Config{
+		Name:    "ctph-options",
+		Input:   input,
+		Options: json.RawMessage(`{"block_size_min":2}`),
+		Golden:  "testdata/golden/ctph-options.trace.json",
+	})
+}
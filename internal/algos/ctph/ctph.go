@@ -1,17 +1,16 @@
 package ctph
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"regexp"
+	"math"
 	"strings"
 
 	"github.com/joekir/algoexplore"
-
-	"github.com/agnivade/levenshtein"
 )
 
 func init() {
@@ -22,64 +21,164 @@ func (ctph *Ctph) Name() string {
 	return "ctph"
 }
 
+// Describe - see algoexplore.Describer interface
+func (ctph *Ctph) Describe() algoexplore.AlgoInfo {
+	return algoexplore.AlgoInfo{
+		DisplayName: "ssdeep",
+		Version:     "1",
+		Description: "Context Triggered Piecewise Hashing, the fuzzy hash used by the ssdeep tool",
+		Reference:   "assets/Kornblum_Identifying_almost_identical_files_using_context_triggered_piecewise_hashing.pdf",
+		Example:     "The quick brown fox jumped over the lazy dog's back",
+	}
+}
+
+// StateSchema - see algoexplore.StateDescriber interface
+func (ctph *Ctph) StateSchema() algoexplore.StateSchema {
+	return algoexplore.StateSchema{Fields: []algoexplore.FieldSchema{
+		{Path: "is_trigger1", Label: "ModBS", Kind: algoexplore.KindTrigger, Order: 0},
+		{Path: "is_trigger2", Label: "Mod2BS", Kind: algoexplore.KindTrigger, Order: 1},
+		{Path: "rolling_hash.window", Label: "Window Array (hex)", Kind: algoexplore.KindByteArray, Bits: 8, Order: 2},
+		{Path: "rolling_hash.x", Label: "X Value", Kind: algoexplore.KindScalar, Bits: 32, Order: 3},
+		{Path: "rolling_hash.y", Label: "Y Value", Kind: algoexplore.KindScalar, Bits: 32, Order: 4},
+		{Path: "rolling_hash.z", Label: "Z Value", Kind: algoexplore.KindScalar, Bits: 32, Order: 5},
+		{Path: "hash1", Label: "FNV Hash 1", Kind: algoexplore.KindRegister, Bits: 32, Order: 6},
+		{Path: "hash2", Label: "FNV Hash 2", Kind: algoexplore.KindRegister, Bits: 32, Order: 7},
+		{Path: "block_size", Label: "Block Size", Kind: algoexplore.KindOutput, Order: 8},
+		{Path: "sig1", Label: "Signature 1", Kind: algoexplore.KindOutput, Order: 9},
+		{Path: "sig2", Label: "Signature 2", Kind: algoexplore.KindOutput, Order: 10},
+	}}
+}
+
 // Init - see algoexplore.AlgoWorker interface
-func (ctph *Ctph) Init(InputLen int) {
+func (ctph *Ctph) Init(InputLen int) error {
+	return ctph.InitWithOptions(InputLen, nil)
+}
+
+// InitWithOptions - see algoexplore.OptionsInitializer interface
+// The options are the JSON Params, any left out keep their ssdeep values
+func (ctph *Ctph) InitWithOptions(InputLen int, raw json.RawMessage) error {
 	if InputLen < 1 {
-		log.Fatal("invalid input length")
+		return errors.New("invalid input length")
+	}
+
+	params := DefaultParams()
+	if len(raw) > 0 {
+		var r io.Reader
+		r = bytes.NewReader(raw)
+		if err := algoexplore.StrictUnmarshalJSON(&r, &params); err != nil {
+			return err
+		}
+	}
+	if err := params.Validate(); err != nil {
+		return err
 	}
 
+	ctph.Params = params
 	ctph.InputLen = InputLen
 	ctph.Retry = true
-	ctph.Bs = calcInitBlockSize(uint32(InputLen))
+	ctph.IsTrigger1, ctph.IsTrigger2 = false, false
+	ctph.Bs = params.initBlockSize(uint64(InputLen))
 	ctph.reset()
+	return nil
 }
 
 // Step - see algoexplore.AlgoWorker interface
-func (ctph *Ctph) Step(d byte) {
+func (ctph *Ctph) Step(d byte) error {
+	if ctph.Done() {
+		return errors.New("the hash is done, it takes no more steps")
+	}
 	ctph.Index++
 	if ctph.Index >= ctph.InputLen {
-		ctph.Sig1 += string(b64Chars[ctph.Hash1.Sum32()&0x3F])
-		ctph.Sig2 += string(b64Chars[ctph.Hash2.Sum32()&0x3F])
+		// ssdeep only digests what is left in the piece hashes when the rolling
+		// hash at the last byte is non-zero, otherwise it keeps the character
+		// from the last trigger after the signature filled up, if there was one
+		digestLen := uint32(len(ctph.Sig1))
+		if ctph.Rh.sum() != 0 {
+			ctph.Sig1 += ctph.Params.char(ctph.Hash1)
+			ctph.Sig2 += ctph.Params.char(ctph.Hash2)
+		} else {
+			ctph.Sig1 += ctph.Tail1
+			ctph.Sig2 += ctph.Tail2
+		}
 
-		if uint32(len(ctph.Sig1)) >= ssLength/2 || ctph.Bs == blockSizeMin {
+		if digestLen >= ctph.Params.SigLength/2 || ctph.Bs == ctph.Params.BlockSizeMin {
 			ctph.Retry = false
-			return
+			return nil
 		}
 
 		ctph.reset()
 		ctph.Bs = ctph.Bs / 2
-		return
+		return nil
 	}
 
 	rs := ctph.Rh.hash(d)
 	if _, err := ctph.Hash1.Write([]byte{d}); err != nil {
-		log.Fatal(err)
+		return err
 	}
 	if _, err := ctph.Hash2.Write([]byte{d}); err != nil {
-		log.Fatal(err)
+		return err
 	}
 	ctph.IsTrigger1, ctph.IsTrigger2 = false, false
 
+	// Once a signature is full its piece hash is no longer reset, so the last
+	// character covers everything after the final piece (64 and 32 chars max
+	// with the default signature length)
 	if mod := rs % ctph.Bs; mod == ctph.Bs-1 {
-		ctph.Sig1 += string(b64Chars[ctph.Hash1.Sum32()&0x3F])
 		ctph.IsTrigger1 = true
-		ctph.Hash1.Reset() // reinit the hash
+		if c := ctph.Params.char(ctph.Hash1); uint32(len(ctph.Sig1)) < ctph.Params.SigLength-1 {
+			ctph.Sig1 += c
+			ctph.Hash1.Reset() // reinit the hash
+		} else {
+			ctph.Tail1 = c
+		}
 	}
 
 	if mod := rs % (2 * ctph.Bs); mod == (2*ctph.Bs)-1 {
-		ctph.Sig2 += string(b64Chars[ctph.Hash2.Sum32()&0x3F])
 		ctph.IsTrigger2 = true
-		ctph.Hash2.Reset() // reinit the hash
+		if c := ctph.Params.char(ctph.Hash2); uint32(len(ctph.Sig2)) < ctph.Params.SigLength/2-1 {
+			ctph.Sig2 += c
+			ctph.Hash2.Reset() // reinit the hash
+		} else {
+			ctph.Tail2 = c
+		}
 	}
+	return nil
+}
+
+// Done - see algoexplore.AlgoWorker interface
+// The hash is done once a pass produces a long enough signature
+func (ctph *Ctph) Done() bool {
+	return ctph.InputLen > 0 && !ctph.Retry
+}
+
+// Output - see algoexplore.AlgoWorker interface
+// Until Done the signatures are only those of the pass so far
+func (ctph *Ctph) Output() (string, error) {
+	return ctph.printSSDeep(), nil
+}
+
+// NamedOutputs - see algoexplore.NamedOutputter interface
+func (ctph *Ctph) NamedOutputs() (map[string]string, error) {
+	return map[string]string{
+		"block_size": fmt.Sprint(ctph.Bs),
+		"sig1":       ctph.Sig1,
+		"sig2":       ctph.Sig2,
+	}, nil
+}
+
+// Position - see algoexplore.Positioner interface
+// A retry restarts the index, so the whole input is stepped again
+func (ctph *Ctph) Position() int {
+	return ctph.Index + 1
 }
 
 // SerializeState - see algoexplore.AlgoWorker interface
-func (ctph *Ctph) SerializeState() string {
+func (ctph *Ctph) SerializeState() (string, error) {
 	byteArray, err := json.Marshal(ctph)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
-	return string(byteArray)
+	return string(byteArray), nil
 }
 
 // DeserializeState - see algoexplore.AlgoWorker interface
@@ -87,52 +186,73 @@ func (ctph *Ctph) SerializeState() string {
 func (ctph *Ctph) DeserializeState(state string) error {
 	var r io.Reader
 	r = strings.NewReader(state)
-	return algoexplore.StrictUnmarshalJSON(&r, &ctph)
+	if err := algoexplore.StrictUnmarshalJSON(&r, &ctph); err != nil {
+		return err
+	}
+
+	// states serialized before the parameters were configurable are ssdeep's
+	if ctph.Params == (Params{}) {
+		ctph.Params = DefaultParams()
+	}
+	return ctph.Params.Validate()
 }
 
-// Implementation based on https://github.com/ssdeep-project/ssdeep/blob/master/fuzzy.c#L383
+// calcInitBlockSize - the block size ssdeep first tries for an input of length u
 func calcInitBlockSize(u uint32) uint32 {
-	var bi uint32
-	for (blockSizeMin<<bi)*ssLength < u {
-		bi++
+	return DefaultParams().initBlockSize(uint64(u))
+}
+
+// Hash returns the ssdeep signature of data, hashed in a single pass by a Stream
+func Hash(data []byte) (string, error) {
+	s := NewStream()
+	if _, err := s.Write(data); err != nil {
+		return "", err
 	}
 
-	return blockSizeMin << bi
+	return s.Sum(), nil
 }
 
-/*
-* Compares 2 CTPH Signatures (s1 and s2) of the form:
-* 24:O7XC9FZ2LBfaW3h+XdcDljuQJtNMMqF5DjQuwM0OHC:O7S9FZ2LwWEdcM6tNMjDEuwwHC
-* <blocksize>:<sigpart1>:<sigpart2>
-*
-* The 2006 paper recommends using levenshtein distance to compare
-*
-* This function will return the distance as a positive integer
- */
-func compareCtphSignatures(s1, s2 string) (int, error) {
-	if matched, err := regexp.MatchString(ssSigPattern, s1); err != nil || !matched {
-		return -1, errors.New("invalid pattern in string 1")
+// HashSteps returns the ssdeep signature of the size bytes read from r by
+// stepping a Ctph through them, as the web server visualizes, pass by pass.
+// r is rewound to its start for each retry at a smaller block size.
+func HashSteps(r io.ReadSeeker, size int64) (string, error) {
+	if size == 0 {
+		return fmt.Sprintf("%d::", blockSizeMin), nil
 	}
-
-	if matched, err := regexp.MatchString(ssSigPattern, s2); err != nil || !matched {
-		return -1, errors.New("invalid pattern in string 2")
+	if size > math.MaxUint32 {
+		return "", errors.New("input too large for ssdeep")
 	}
 
-	Sig1 := strings.Split(s1, ":")
-	Sig2 := strings.Split(s2, ":")
-
-	if Sig1[0] != Sig2[0] {
-		return -1, errors.New("blocksize mismatch")
+	ctph := new(Ctph)
+	if err := ctph.Init(int(size)); err != nil {
+		return "", err
 	}
 
-	firstDiff := levenshtein.ComputeDistance(Sig1[1], Sig2[1])
-	secondDiff := levenshtein.ComputeDistance(Sig1[2], Sig2[2])
+	br := bufio.NewReader(r)
+	for !ctph.Done() {
+		i := ctph.Position()
+		if i == 0 {
+			if _, err := r.Seek(0, io.SeekStart); err != nil {
+				return "", err
+			}
+			br.Reset(r)
+		}
 
-	if secondDiff < firstDiff {
-		return secondDiff, nil
+		var d byte
+		if int64(i) < size {
+			var err error
+			if d, err = br.ReadByte(); err == io.EOF {
+				return "", io.ErrUnexpectedEOF
+			} else if err != nil {
+				return "", err
+			}
+		}
+		if err := ctph.Step(d); err != nil {
+			return "", err
+		}
 	}
 
-	return firstDiff, nil
+	return ctph.printSSDeep(), nil
 }
 
 func (ctph Ctph) printSSDeep() string {
@@ -142,35 +262,15 @@ func (ctph Ctph) printSSDeep() string {
 func (ctph *Ctph) reset() {
 	ctph.Hash1, ctph.Hash2 = *NewFNV(), *NewFNV()
 	ctph.Index = -1
-	ctph.Rh = *newRollingHash()
+	ctph.Rh = *newRollingHash(ctph.Params.WindowSize)
 	ctph.Sig1, ctph.Sig2 = "", ""
-}
-
-func newRollingHash() *RollingHash {
-	return &RollingHash{
-		Size:   windowSize,
-		Window: make([]uint32, windowSize),
-	}
-}
-
-func (rh *RollingHash) hash(d byte) uint32 {
-	dint := uint32(d)
-	rh.Y = rh.Y - rh.X
-	rh.Y = rh.Y + rh.Size*dint
-	rh.X = rh.X + dint
-	rh.X = rh.X - rh.Window[rh.C%rh.Size]
-	rh.Window[rh.C%rh.Size] = dint
-	rh.C = rh.C + 1
-	rh.Z = rh.Z << 5
-	rh.Z = rh.Z ^ dint
-
-	return (rh.X + rh.Y + rh.Z)
+	ctph.Tail1, ctph.Tail2 = "", ""
 }
 
 const (
 	b64Chars     string = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
 	ssLength     uint32 = 64
-	ssSigPattern string = "^\\d+:[0-9a-zA-Z+\\/]+:[0-9a-zA-Z+\\/]+$"
+	ssSigPattern string = "^(\\d+):([0-9a-zA-Z+\\/]*):([0-9a-zA-Z+\\/]*)(,.*)?$"
 	windowSize   uint32 = 7
 	blockSizeMin uint32 = 3
 )
@@ -189,14 +289,7 @@ type Ctph struct {
 	Rh         RollingHash `json:"rolling_hash"`
 	Sig1       string      `json:"sig1"`
 	Sig2       string      `json:"sig2"`
-}
-
-// RollingHash - SubType of CTPH to maintain a rolling-window hash
-type RollingHash struct {
-	X      uint32   `json:"x"`
-	Y      uint32   `json:"y"`
-	Z      uint32   `json:"z"`
-	C      uint32   `json:"c"`
-	Size   uint32   `json:"size"`
-	Window []uint32 `json:"window"`
+	Tail1      string      `json:"tail1"`
+	Tail2      string      `json:"tail2"`
+	Params     Params      `json:"params"`
 }
@@ -1,15 +1,40 @@
 package ctph
 
 import (
+	"bytes"
+	"io"
 	"io/ioutil"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/joekir/algoexplore"
 )
 
+// serialize returns the state of algo, failing the test if it cannot
+func serialize(t *testing.T, algo algoexplore.AlgoPlugin) string {
+	t.Helper()
+
+	state, err := algo.SerializeState()
+	if err != nil {
+		t.Fatalf("failed to serialize state: %v", err)
+	}
+	return state
+}
+
+// hash returns the ssdeep signature of data, failing the test if it cannot
+func hash(t *testing.T, data []byte) string {
+	t.Helper()
+
+	h, err := Hash(data)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	return h
+}
+
 func TestRollingHash(t *testing.T) {
-	rh := newRollingHash()
+	rh := newRollingHash(windowSize)
 
 	x := rh.hash(byte(3))
 	if x != 27 {
@@ -99,36 +124,29 @@ func TestCtphHash_WithCrowAndFox_MatchesExistingTool(t *testing.T) {
 }
 
 func TestCtphHash_WithWebsiteDefault_MatchesExistingTool(t *testing.T) {
-	data := []byte("Fuzzy Wuzzy was a bear, Fuzzy Wuzzy had no hair")
+	// the website's default text is among the text test vectors, stepped here
+	// byte by byte as the web server does
+	_, vectors := readVectors(t)
+	for _, v := range vectors {
+		if v.gen != "text" {
+			continue
+		}
 
-	ctph := new(Ctph)
-	ctph.Init(len(data))
+		ctph := new(Ctph)
+		ctph.Init(len(v.data))
 
-	for ctph.Retry {
-		for _, b := range data {
-			ctph.Step(b)
-			if !ctph.Retry {
-				break
+		for ctph.Retry {
+			var d byte
+			if i := ctph.Position(); i < len(v.data) {
+				d = v.data[i]
 			}
+			ctph.Step(d)
 		}
-	}
-	h := ctph.printSSDeep()
-	s := strings.Split(h, ":")
-
-	// NOTE: there is something wrong in this impl with the final character of
-	// 		 hash1 and hash2, so I'm not comparing that character
-	//		 I've tried backtracking through the c code ssdeep impl to v2.13
-	//		 It's DEFINITELY the implementation here, no that one that changed
+		h := ctph.printSSDeep()
 
-	expectedSSDeepHash1Prefix := "+0t8XXJFg0D8SmN" // mine returns 'n' should be 'v'
-	expectedSSDeepHash2Prefix := "++0F7Dx"         // mine returns 'S', should be 'c'
-
-	if !strings.HasPrefix(s[1], expectedSSDeepHash1Prefix) {
-		t.Fatalf("Unexpected hash: %s", s[1])
-	}
-
-	if !strings.HasPrefix(s[2], expectedSSDeepHash2Prefix) {
-		t.Fatalf("Unexpected hash: %s", s[2])
+		if !cmp.Equal(v.expected, h) {
+			t.Fatalf("%s: unexpected hash: %s", v, cmp.Diff(v.expected, h))
+		}
 	}
 }
 
@@ -153,35 +171,38 @@ func TestCompare_WithCrowAndFoxSlightlyTweaked_IsSimilar(t *testing.T) {
 	}
 	newHash := ctph.printSSDeep()
 
-	result, err := compareCtphSignatures(original, newHash)
+	result, err := Score(original, newHash)
 	if err != nil {
 		t.Fatalf("Failed to compare hashes: %v", err)
 	}
 
-	if result > 5 {
+	if result < 90 || result == 100 {
 		t.Fatalf("comparison score (%d) incorrect for hashes:\n%s\n%s\n", result, original, newHash)
 	}
 }
 
 func TestCompare_WithInvalidSignatures_ThrowsError(t *testing.T) {
-	_, err := compareCtphSignatures("24O7XC9FZ2LBfaW3hM6tNMjDEuwwHC", "24:O7XC9FZ2LBfaW3h+XdcDljuQJtNMMqF5DjQuwM0OHC:O7S9FZ2LwWEdcM6tNMjDEuwwHC")
+	_, err := Score("24O7XC9FZ2LBfaW3hM6tNMjDEuwwHC", "24:O7XC9FZ2LBfaW3h+XdcDljuQJtNMMqF5DjQuwM0OHC:O7S9FZ2LwWEdcM6tNMjDEuwwHC")
 
 	if err == nil || err.Error() != "invalid pattern in string 1" {
 		t.Fatalf("Compare should have failed with invalid sig pattern")
 	}
 
-	_, err = compareCtphSignatures("24:O7XC9FZ2LBfaW3h+XdcDljuQJtNMMqF5DjQuwM0OHC:O7S9FZ2LwWEdcM6tNMjDEuwwHC", "24O7XC9FZ2LBfaW3hM6tNMjDEuwwHC")
+	_, err = Score("24:O7XC9FZ2LBfaW3h+XdcDljuQJtNMMqF5DjQuwM0OHC:O7S9FZ2LwWEdcM6tNMjDEuwwHC", "24O7XC9FZ2LBfaW3hM6tNMjDEuwwHC")
 
 	if err == nil || err.Error() != "invalid pattern in string 2" {
 		t.Fatalf("Compare should have failed with invalid sig pattern")
 	}
 }
 
-func TestCompare_WithIncompatibleSignatures_ThrowsError(t *testing.T) {
-	_, err := compareCtphSignatures("24:0:0", "12:O:O")
+func TestCompare_WithIncompatibleSignatures_ScoresZero(t *testing.T) {
+	score, err := Score("24:O7XC9FZ2LBfaW3h:O7S9FZ2L", "96:O7XC9FZ2LBfaW3h:O7S9FZ2L")
+	if err != nil {
+		t.Fatalf("Failed to compare hashes: %v", err)
+	}
 
-	if err == nil || err.Error() != "blocksize mismatch" {
-		t.Fatalf("Compare should throw blocksize mismatch")
+	if score != 0 {
+		t.Fatalf("Compare should score blocksizes more than double apart as 0, got %d", score)
 	}
 }
 
@@ -198,7 +219,84 @@ func TestDeserializeState_withValidJson_parsestoCtph(t *testing.T) {
 func TestSerializeState_withCtphStruct_serializesToCorrectJSON(t *testing.T) {
 	ctph := new(Ctph)
 	ctph.InputLen = 37331
-	if !strings.Contains(ctph.SerializeState(), "37331") {
+	if !strings.Contains(serialize(t, ctph), "37331") {
 		t.Fatal("expected serialized data to contain 37331 but it did not")
 	}
 }
+
+func TestInit_withInvalidLength_ReturnsError(t *testing.T) {
+	for _, algo := range []algoexplore.AlgoPlugin{new(Ctph), new(Stream), new(RollingHashAlgo), new(FNV)} {
+		if err := algo.Init(0); err == nil || err.Error() != "invalid input length" {
+			t.Errorf("%s: expected an invalid input length error, got %v", algo.Name(), err)
+		}
+	}
+}
+
+func TestStep_onceDone_ReturnsErrorWithoutChangingTheOutput(t *testing.T) {
+	data := []byte("abc")
+	for _, algo := range []algoexplore.AlgoPlugin{new(Ctph), new(Stream), new(RollingHashAlgo), new(FNV)} {
+		if err := algo.Init(len(data)); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; !algo.Done(); i++ {
+			if err := algo.Step(data[i%len(data)]); err != nil {
+				t.Fatalf("%s: %v", algo.Name(), err)
+			}
+		}
+		output, _ := algo.Output()
+
+		if err := algo.Step('a'); err == nil {
+			t.Errorf("%s: expected an error stepping once done", algo.Name())
+		}
+		if after, _ := algo.Output(); after != output {
+			t.Errorf("%s: expected the output to stay %s, got %s", algo.Name(), output, after)
+		}
+	}
+}
+
+func TestHash_WithMobyDick_MatchesSteppedHash(t *testing.T) {
+	expectedSSDeep := "384:S8G2SPXyDhU4nAnaFBtFrSx7zD74Z/kFSD:SM80YaFBtQDcZ/MSD"
+
+	data, err := ioutil.ReadFile("testdata/mobydick.txt")
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+
+	if h := hash(t, data); !cmp.Equal(expectedSSDeep, h) {
+		t.Fatalf("Unexpected hash: %s", cmp.Diff(expectedSSDeep, h))
+	}
+}
+
+func TestHashSteps_WithShortReader_ReturnsError(t *testing.T) {
+	if _, err := HashSteps(strings.NewReader("abc"), 4); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected an unexpected EOF, got %v", err)
+	}
+}
+
+func TestHash_WithEmptyInput_ReturnsEmptySignature(t *testing.T) {
+	if h := hash(t, nil); h != "3::" {
+		t.Fatalf("Unexpected hash: %s", h)
+	}
+}
+
+func TestHashAndHashChunks_WithTestVectors_MatchSsdeep(t *testing.T) {
+	lines, vectors := readVectors(t)
+	if len(*ssdeepBin) > 0 {
+		regenerateVectors(t, lines, vectors)
+		return
+	}
+
+	for _, v := range vectors {
+		// Hash runs on the Stream, HashChunks and HashSteps on the stepped
+		// Ctph, both engines must match ssdeep
+		if h := hash(t, v.data); h != v.expected {
+			t.Errorf("%s: expected %s, got %s from Hash", v, v.expected, h)
+		}
+		if h, _, err := HashChunks(v.data); err != nil || h != v.expected {
+			t.Errorf("%s: expected %s, got %s (%v) from HashChunks", v, v.expected, h, err)
+		}
+		if h, err := HashSteps(bytes.NewReader(v.data), int64(len(v.data))); err != nil || h != v.expected {
+			t.Errorf("%s: expected %s, got %s (%v) from HashSteps", v, v.expected, h, err)
+		}
+	}
+}
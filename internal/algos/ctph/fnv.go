@@ -1,5 +1,15 @@
 package ctph
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/joekir/algoexplore"
+)
+
 // Custom implementation of FNV32 based off the implementation used in SSDEEP:
 // https://github.com/ssdeep-project/ssdeep/blob/master/fuzzy.c#L109
 //
@@ -7,6 +17,10 @@ package ctph
 //
 // The primary difference is the use of a non-standard FNV offset, 0x28021967.
 
+func init() {
+	algoexplore.Register(func() algoexplore.AlgoPlugin { return &FNV{} })
+}
+
 const (
 	offset = 0x28021967 // SSDEEP specific FNV offset value
 	prime  = 16777619   // Standard FNV 32 bit prime
@@ -23,6 +37,104 @@ func NewFNV() *Sum32 {
 // Reset the hash to the custom SSDEEP offset.
 func (s *Sum32) Reset() { *s = offset }
 
+// FNV - the SSDEEP FNV32 on its own, stepped a byte at a time so that the
+// multiply and the xor of each byte can be seen apart
+// struct that contains the algorithm's state
+type FNV struct {
+	Index    int    `json:"index"`
+	InputLen int    `json:"input_length"`
+	Byte     byte   `json:"byte"`
+	Previous Sum32  `json:"previous"`
+	Product  Sum32  `json:"product"`
+	Hash     Sum32  `json:"hash"`
+	Digest   string `json:"digest"`
+	Char     string `json:"char"`
+}
+
+func (f *FNV) Name() string {
+	return "fnv32-ssdeep"
+}
+
+// Describe - see algoexplore.Describer interface
+func (f *FNV) Describe() algoexplore.AlgoInfo {
+	return algoexplore.AlgoInfo{
+		DisplayName: "ssdeep FNV-1",
+		Version:     "1",
+		Description: "The 32 bit FNV-1 piece hash of ssdeep, multiply by the FNV prime then xor in the byte",
+		Reference:   "http://www.isthe.com/chongo/tech/comp/fnv/index.html",
+		Example:     "The quick brown fox jumped over the lazy dog's back",
+	}
+}
+
+// StateSchema - see algoexplore.StateDescriber interface
+func (f *FNV) StateSchema() algoexplore.StateSchema {
+	return algoexplore.StateSchema{Fields: []algoexplore.FieldSchema{
+		{Path: "previous", Label: "Hash", Kind: algoexplore.KindRegister, Bits: 32, Order: 0},
+		{Path: "product", Label: "Hash * 16777619", Kind: algoexplore.KindRegister, Bits: 32, Order: 1},
+		{Path: "byte", Label: "Byte", Kind: algoexplore.KindRegister, Bits: 8, Order: 2},
+		{Path: "hash", Label: "(Hash * 16777619) ^ Byte", Kind: algoexplore.KindRegister, Bits: 32, Order: 3},
+		{Path: "char", Label: "Base64 of low 6 bits", Kind: algoexplore.KindScalar, Order: 4},
+		{Path: "digest", Label: "FNV Hash (hex)", Kind: algoexplore.KindOutput, Order: 5},
+	}}
+}
+
+// Init - see algoexplore.AlgoWorker interface
+func (f *FNV) Init(InputLen int) error {
+	if InputLen < 1 {
+		return errors.New("invalid input length")
+	}
+
+	*f = FNV{Index: -1, InputLen: InputLen, Hash: *NewFNV()}
+	f.Previous, f.Product = f.Hash, f.Hash
+	f.digest()
+	return nil
+}
+
+// Step - see algoexplore.AlgoWorker interface
+func (f *FNV) Step(d byte) error {
+	if f.Done() {
+		return errors.New("the hash is done, it takes no more steps")
+	}
+	f.Index++
+	f.Byte = d
+	f.Previous = f.Hash
+	f.Product = f.Hash * prime
+	f.Hash = f.Product ^ Sum32(d)
+	f.digest()
+	return nil
+}
+
+// Done - see algoexplore.AlgoWorker interface
+func (f *FNV) Done() bool {
+	return f.InputLen > 0 && f.Index+1 >= f.InputLen
+}
+
+// Output - see algoexplore.AlgoWorker interface
+func (f *FNV) Output() (string, error) {
+	return f.Digest, nil
+}
+
+// SerializeState - see algoexplore.AlgoWorker interface
+func (f *FNV) SerializeState() (string, error) {
+	byteArray, err := json.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	return string(byteArray), nil
+}
+
+// DeserializeState - see algoexplore.AlgoWorker interface
+func (f *FNV) DeserializeState(state string) error {
+	var r io.Reader
+	r = strings.NewReader(state)
+	return algoexplore.StrictUnmarshalJSON(&r, &f)
+}
+
+func (f *FNV) digest() {
+	f.Digest = fmt.Sprintf("%08x", f.Hash.Sum32())
+	f.Char = string(b64Chars[f.Hash.Sum32()&0x3F])
+}
+
 //////////////////////////////////////////////////////////////////////////////
 // Everything from this point on is identical to stdlib FNV32 implementations.
 //////////////////////////////////////////////////////////////////////////////
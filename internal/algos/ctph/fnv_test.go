@@ -0,0 +1,45 @@
+package ctph
+
+import (
+	"testing"
+)
+
+func TestFNV_Stepped_MatchesSum32(t *testing.T) {
+	data := []byte("The quick brown fox jumped over the lazy dog's back")
+
+	f := new(FNV)
+	f.Init(len(data))
+	expected := NewFNV()
+
+	for _, b := range data {
+		state := serialize(t, f)
+		f = new(FNV)
+		if err := f.DeserializeState(state); err != nil {
+			t.Fatalf("failed to deserialize state: %v", err)
+		}
+
+		previous := f.Hash
+		f.Step(b)
+		expected.Write([]byte{b})
+
+		if f.Hash != *expected {
+			t.Fatalf("byte %d: expected %08x, got %08x", f.Index, uint32(*expected), uint32(f.Hash))
+		}
+		if f.Previous != previous || f.Product != previous*prime || f.Product^Sum32(b) != f.Hash {
+			t.Fatalf("byte %d: expected the multiply then the xor, got %+v", f.Index, f)
+		}
+	}
+
+	if f.Char != string(b64Chars[expected.Sum32()&0x3F]) {
+		t.Fatalf("expected the ssdeep character of %08x, got %s", expected.Sum32(), f.Char)
+	}
+}
+
+func TestFNV_Init_StartsAtTheSsdeepOffset(t *testing.T) {
+	f := new(FNV)
+	f.Init(1)
+
+	if f.Digest != "28021967" {
+		t.Fatalf("expected the ssdeep offset, got %s", f.Digest)
+	}
+}
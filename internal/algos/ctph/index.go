@@ -0,0 +1,255 @@
+package ctph
+
+// An index of CTPH signatures that only scores the stored signatures that
+// could match a query, as ssdeep backed databases do.
+//
+// Score is 0 unless two signature parts at the same block size share a run of
+// windowSize characters (see hasCommonSubstring), or the first parts are
+// identical at the same block size. So every part is bucketed by its block
+// size (double for the second part) and each of its windowSize-grams, and a
+// query is only scored against the signatures in the buckets of its own parts.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// indexVersion is the version of the format an Index is saved in
+const indexVersion = 1
+
+// Index - a set of CTPH signatures, by ID, to find those similar to another
+// signature without scoring every one. It is safe for concurrent use.
+type Index struct {
+	mu         sync.RWMutex
+	signatures map[string]string
+	buckets    map[indexKey]map[string]struct{}
+}
+
+// indexKey - a bucket of the Index, a windowSize-gram of a signature part at
+// its block size, or the whole of a first part when exact
+type indexKey struct {
+	bs    uint64
+	gram  string
+	exact bool
+}
+
+// IndexMatch - a stored signature that scored against a query
+type IndexMatch struct {
+	ID        string `json:"id"`
+	Signature string `json:"signature"`
+	Score     int    `json:"score"`
+}
+
+// savedIndex - the format an Index is saved in
+type savedIndex struct {
+	Version    int          `json:"version"`
+	Signatures []savedEntry `json:"signatures"`
+}
+
+type savedEntry struct {
+	ID        string `json:"id"`
+	Signature string `json:"signature"`
+}
+
+// NewIndex returns an empty Index
+func NewIndex() *Index {
+	return &Index{
+		signatures: map[string]string{},
+		buckets:    map[indexKey]map[string]struct{}{},
+	}
+}
+
+// Len returns how many signatures are in the index
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.signatures)
+}
+
+// Add stores the signature under the id, replacing any signature already
+// stored under it. Malformed signatures return an error
+func (ix *Index) Add(id, signature string) error {
+	keys, err := indexKeys(signature)
+	if err != nil {
+		return err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+	ix.signatures[id] = signature
+	for _, k := range keys {
+		ids, ok := ix.buckets[k]
+		if !ok {
+			ids = map[string]struct{}{}
+			ix.buckets[k] = ids
+		}
+		ids[id] = struct{}{}
+	}
+	return nil
+}
+
+// Remove removes the signature stored under the id, reporting whether there was one
+func (ix *Index) Remove(id string) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	return ix.remove(id)
+}
+
+func (ix *Index) remove(id string) bool {
+	signature, ok := ix.signatures[id]
+	if !ok {
+		return false
+	}
+
+	// it was indexed, so it parses
+	keys, _ := indexKeys(signature)
+	for _, k := range keys {
+		delete(ix.buckets[k], id)
+		if len(ix.buckets[k]) < 1 {
+			delete(ix.buckets, k)
+		}
+	}
+	delete(ix.signatures, id)
+	return true
+}
+
+// Query returns the stored signatures that Score above the threshold against
+// the signature, from the highest score then by ID. Only the signatures that
+// share a bucket with it are scored.
+func (ix *Index) Query(signature string, threshold int) ([]IndexMatch, error) {
+	keys, err := indexKeys(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	candidates := map[string]struct{}{}
+	for _, k := range keys {
+		for id := range ix.buckets[k] {
+			candidates[id] = struct{}{}
+		}
+	}
+
+	matches := []IndexMatch{}
+	for id := range candidates {
+		stored := ix.signatures[id]
+		score, err := Score(signature, stored)
+		if err != nil {
+			return nil, err
+		}
+		if score > threshold {
+			matches = append(matches, IndexMatch{ID: id, Signature: stored, Score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches, nil
+}
+
+// Save writes the signatures of the index as JSON, for LoadIndex
+func (ix *Index) Save(w io.Writer) error {
+	ix.mu.RLock()
+	saved := savedIndex{Version: indexVersion, Signatures: make([]savedEntry, 0, len(ix.signatures))}
+	for id, signature := range ix.signatures {
+		saved.Signatures = append(saved.Signatures, savedEntry{ID: id, Signature: signature})
+	}
+	ix.mu.RUnlock()
+
+	sort.Slice(saved.Signatures, func(i, j int) bool { return saved.Signatures[i].ID < saved.Signatures[j].ID })
+	return json.NewEncoder(w).Encode(saved)
+}
+
+// SaveFile saves the index to the file at path, replacing it whole so that
+// a crash never leaves a half written index
+func (ix *Index) SaveFile(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if err := ix.Save(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadIndex reads an index written by Save, rebuilding its buckets
+func LoadIndex(r io.Reader) (*Index, error) {
+	var saved savedIndex
+	if err := json.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, err
+	}
+	if saved.Version != indexVersion {
+		return nil, fmt.Errorf("unsupported index version %d", saved.Version)
+	}
+
+	ix := NewIndex()
+	for _, e := range saved.Signatures {
+		if err := ix.Add(e.ID, e.Signature); err != nil {
+			return nil, fmt.Errorf("%s: %v", e.ID, err)
+		}
+	}
+	return ix, nil
+}
+
+// LoadIndexFile reads an index saved to the file at path by SaveFile
+func LoadIndexFile(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadIndex(f)
+}
+
+// indexKeys lists the buckets a signature is stored in, and looked up by
+func indexKeys(signature string) ([]indexKey, error) {
+	bs, part1, part2, ok := parseSignature(signature)
+	if !ok {
+		return nil, fmt.Errorf("invalid signature: %q", signature)
+	}
+
+	part1, part2 = eliminateSequences(part1), eliminateSequences(part2)
+	keys := []indexKey{{bs: bs, gram: part1, exact: true}}
+	keys = appendGrams(keys, bs, part1)
+	return appendGrams(keys, bs*2, part2), nil
+}
+
+// appendGrams appends a key for each distinct windowSize-gram of the part
+func appendGrams(keys []indexKey, bs uint64, part string) []indexKey {
+	n := int(windowSize)
+	seen := map[string]bool{}
+	for i := 0; i+n <= len(part); i++ {
+		if gram := part[i : i+n]; !seen[gram] {
+			seen[gram] = true
+			keys = append(keys, indexKey{bs: bs, gram: gram})
+		}
+	}
+	return keys
+}
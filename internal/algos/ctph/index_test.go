@@ -0,0 +1,260 @@
+package ctph
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func readTestFile(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+	return data
+}
+
+// randomPart returns n random signature characters
+func randomPart(rng *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = b64Chars[rng.Intn(len(b64Chars))]
+	}
+	return string(b)
+}
+
+// mutate changes count random characters of the part
+func mutate(rng *rand.Rand, part string, count int) string {
+	b := []byte(part)
+	for i := 0; i < count && len(b) > 0; i++ {
+		b[rng.Intn(len(b))] = b64Chars[rng.Intn(len(b64Chars))]
+	}
+	return string(b)
+}
+
+// randomSignatures returns families of related signatures: a random one, a
+// few edits of it and one at double its block size, as a tweaked input would
+// hash to. Some are too short to share a window with anything.
+func randomSignatures(rng *rand.Rand, families int) []string {
+	var signatures []string
+	for f := 0; f < families; f++ {
+		bs := uint64(blockSizeMin) << uint(rng.Intn(8))
+		part1, part2 := randomPart(rng, 8+rng.Intn(56)), randomPart(rng, 4+rng.Intn(28))
+		if f%10 == 0 {
+			part1, part2 = randomPart(rng, rng.Intn(int(windowSize))), randomPart(rng, rng.Intn(int(windowSize)))
+		}
+
+		signatures = append(signatures,
+			fmt.Sprintf("%d:%s:%s", bs, part1, part2),
+			fmt.Sprintf("%d:%s:%s", bs, mutate(rng, part1, 2), mutate(rng, part2, 1)),
+			fmt.Sprintf("%d:%s:%s", bs, mutate(rng, part1, 12), part2),
+			fmt.Sprintf("%d:%s:%s", bs*2, mutate(rng, part2, 1), randomPart(rng, len(part2)/2)))
+	}
+	return signatures
+}
+
+// bruteForce scores the signature against every stored signature
+func bruteForce(t testing.TB, signatures map[string]string, signature string, threshold int) []IndexMatch {
+	matches := []IndexMatch{}
+	for id, stored := range signatures {
+		score, err := Score(signature, stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score > threshold {
+			matches = append(matches, IndexMatch{ID: id, Signature: stored, Score: score})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches
+}
+
+// newTestIndex indexes the signatures, each by its position
+func newTestIndex(t testing.TB, signatures []string) (*Index, map[string]string) {
+	ix := NewIndex()
+	byID := map[string]string{}
+	for i, s := range signatures {
+		id := fmt.Sprint(i)
+		if err := ix.Add(id, s); err != nil {
+			t.Fatal(err)
+		}
+		byID[id] = s
+	}
+	return ix, byID
+}
+
+func TestIndex_Query_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	signatures := randomSignatures(rng, 100)
+	ix, byID := newTestIndex(t, signatures)
+
+	if ix.Len() != len(signatures) {
+		t.Fatalf("unexpected length: got %d want %d", ix.Len(), len(signatures))
+	}
+
+	matched := 0
+	for _, s := range append(signatures, randomSignatures(rng, 10)...) {
+		expected := bruteForce(t, byID, s, 0)
+		matches, err := ix.Query(s, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, matches); diff != "" {
+			t.Fatalf("query of %s differs from brute force (-want +got):\n%s", s, diff)
+		}
+		matched += len(matches)
+
+		// a threshold only drops the lower scores
+		above := []IndexMatch{}
+		for _, m := range expected {
+			if m.Score > 50 {
+				above = append(above, m)
+			}
+		}
+		if matches, _ = ix.Query(s, 50); !cmp.Equal(above, matches) {
+			t.Fatalf("query of %s above 50 differs from brute force: got %v want %v", s, matches, above)
+		}
+	}
+
+	if matched <= len(signatures) {
+		t.Fatalf("expected signatures to match more than themselves, got %d matches", matched)
+	}
+}
+
+func TestIndex_Query_withRealHashes_FindsTheTweakedInput(t *testing.T) {
+	original := readTestFile(t, "testdata/crowandthefox.txt")
+	tweaked := bytes.Replace(original, []byte("vous"), []byte("tous"), 1)
+
+	ix := NewIndex()
+	for id, data := range map[string][]byte{
+		"crow":  original,
+		"moby":  readTestFile(t, "testdata/mobydick.txt"),
+		"empty": {},
+	} {
+		if err := ix.Add(id, hash(t, data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matches, err := ix.Query(hash(t, tweaked), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	score, err := Score(hash(t, tweaked), hash(t, original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []IndexMatch{{ID: "crow", Signature: hash(t, original), Score: score}}
+	if diff := cmp.Diff(expected, matches); diff != "" {
+		t.Fatalf("unexpected matches (-want +got):\n%s", diff)
+	}
+}
+
+func TestIndex_AddAndRemove_UpdateTheBuckets(t *testing.T) {
+	const s1, s2 = "3:abcdefgh:abcd", "3:zyxwvuts:zyxw"
+
+	ix := NewIndex()
+	if err := ix.Add("a", s1); err != nil {
+		t.Fatal(err)
+	}
+	// replaces the first signature
+	if err := ix.Add("a", s2); err != nil {
+		t.Fatal(err)
+	}
+	if ix.Len() != 1 {
+		t.Fatalf("expected the signature to be replaced, got %d signatures", ix.Len())
+	}
+
+	if matches, _ := ix.Query(s1, 0); len(matches) != 0 {
+		t.Fatalf("expected the replaced signature to be forgotten, got %v", matches)
+	}
+	if matches, _ := ix.Query(s2, 0); len(matches) != 1 || matches[0].Score != 100 {
+		t.Fatalf("expected the signature to match itself, got %v", matches)
+	}
+
+	if !ix.Remove("a") || ix.Remove("a") {
+		t.Fatal("expected only the first remove to find the signature")
+	}
+	if ix.Len() != 0 || len(ix.buckets) != 0 {
+		t.Fatalf("expected an empty index, got %d signatures in %d buckets", ix.Len(), len(ix.buckets))
+	}
+}
+
+func TestIndex_withInvalidSignatures_ReturnsErrors(t *testing.T) {
+	ix := NewIndex()
+	if err := ix.Add("a", "not a signature"); err == nil {
+		t.Fatal("expected an error adding an invalid signature")
+	}
+	if _, err := ix.Query("3:abc", 0); err == nil {
+		t.Fatal("expected an error querying an invalid signature")
+	}
+}
+
+func TestIndex_SaveFile_LoadsTheSameIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	signatures := randomSignatures(rng, 20)
+	ix, _ := newTestIndex(t, signatures)
+
+	path := filepath.Join(t.TempDir(), "index.json")
+	if err := ix.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadIndexFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(ix.signatures, loaded.signatures); diff != "" {
+		t.Fatalf("loaded signatures differ (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(ix.buckets, loaded.buckets, cmp.AllowUnexported(indexKey{})); diff != "" {
+		t.Fatalf("loaded buckets differ (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadIndex_withUnknownVersion_ReturnsError(t *testing.T) {
+	if _, err := LoadIndex(bytes.NewReader([]byte(`{"version": 2, "signatures": []}`))); err == nil {
+		t.Fatal("expected an error for an unknown version")
+	}
+}
+
+// benchmarkCorpus indexes 100k signatures, 25k families of related ones
+func benchmarkCorpus(b *testing.B) (*Index, map[string]string, []string) {
+	rng := rand.New(rand.NewSource(3))
+	ix, byID := newTestIndex(b, randomSignatures(rng, 25000))
+	return ix, byID, randomSignatures(rng, 100)
+}
+
+func BenchmarkIndex_Query(b *testing.B) {
+	ix, _, queries := benchmarkCorpus(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ix.Query(queries[i%len(queries)], 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIndex_BruteForce(b *testing.B) {
+	_, byID, queries := benchmarkCorpus(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bruteForce(b, byID, queries[i%len(queries)], 0)
+	}
+}
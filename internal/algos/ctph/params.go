@@ -0,0 +1,104 @@
+package ctph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/joekir/algoexplore"
+)
+
+const (
+	maxWindowSize   = 64
+	maxBlockSizeMin = 1 << 20
+	maxSigLength    = 256
+)
+
+// Params - the tunables of a Ctph, persisted in its serialized state
+// DefaultParams are those of ssdeep, signatures hashed with any others can
+// not be compared to ssdeep's, by ssdeep or by Score.
+type Params struct {
+	WindowSize   uint32 `json:"window_size"`
+	BlockSizeMin uint32 `json:"block_size_min"`
+	SigLength    uint32 `json:"signature_length"`
+	Alphabet     string `json:"alphabet"`
+}
+
+// DefaultParams returns the parameters that make Ctph compatible with ssdeep
+func DefaultParams() Params {
+	return Params{
+		WindowSize:   windowSize,
+		BlockSizeMin: blockSizeMin,
+		SigLength:    ssLength,
+		Alphabet:     b64Chars,
+	}
+}
+
+// OptionsSchema - see algoexplore.OptionsInitializer interface
+func (ctph *Ctph) OptionsSchema() algoexplore.OptionsSchema {
+	return algoexplore.OptionsSchema{Options: []algoexplore.OptionSchema{
+		{Name: "window_size", Label: "Rolling Window", Type: algoexplore.OptionInteger,
+			Default: windowSize, Min: 1, Max: maxWindowSize, Order: 0,
+			Help: "bytes the rolling hash is computed over, a larger window cuts pieces less often"},
+		{Name: "block_size_min", Label: "Min Block Size", Type: algoexplore.OptionInteger,
+			Default: blockSizeMin, Min: 1, Max: maxBlockSizeMin, Order: 1,
+			Help: "the smallest block size, larger block sizes double from it"},
+		{Name: "signature_length", Label: "Signature Length", Type: algoexplore.OptionInteger,
+			Default: ssLength, Min: 4, Max: maxSigLength, Order: 2,
+			Help: "an even number of characters, the second signature has half as many"},
+		{Name: "alphabet", Label: "Alphabet", Type: algoexplore.OptionString,
+			Default: b64Chars, Min: 2, Max: 64, Order: 3,
+			Help: "the characters a piece hash is encoded with, a smaller alphabet matches more often"},
+	}}
+}
+
+// Validate checks that the parameters describe a hash that can be computed
+func (p Params) Validate() error {
+	if p.WindowSize < 1 || p.WindowSize > maxWindowSize {
+		return fmt.Errorf("window_size must be between 1 and %d", maxWindowSize)
+	}
+
+	if p.BlockSizeMin < 1 || p.BlockSizeMin > maxBlockSizeMin {
+		return fmt.Errorf("block_size_min must be between 1 and %d", maxBlockSizeMin)
+	}
+
+	// the second signature is half the length of the first, and both need
+	// room for at least one piece before their final character
+	if p.SigLength < 4 || p.SigLength > maxSigLength || p.SigLength%2 != 0 {
+		return fmt.Errorf("signature_length must be an even number between 4 and %d", maxSigLength)
+	}
+
+	if len(p.Alphabet) < 2 || len(p.Alphabet) > 64 {
+		return errors.New("alphabet must have between 2 and 64 characters")
+	}
+	for i, c := range p.Alphabet {
+		if c < '!' || c > '~' || c == ':' || c == ',' {
+			return fmt.Errorf("alphabet character %q is not printable ASCII, or is a signature separator", c)
+		}
+		if strings.IndexRune(p.Alphabet, c) != i {
+			return fmt.Errorf("alphabet character %q is repeated", c)
+		}
+	}
+
+	return nil
+}
+
+// char picks the signature character of a piece hash, for the 64 character
+// ssdeep alphabet this is its least significant 6 bits
+func (p Params) char(h Sum32) string {
+	return string(p.Alphabet[h.Sum32()%uint32(len(p.Alphabet))])
+}
+
+// initBlockSize is the smallest block size, doubling from BlockSizeMin, at
+// which an input of length u could fill the signature. It stops doubling
+// before twice the block size, the second signature's, would overflow.
+// Implementation based on https://github.com/ssdeep-project/ssdeep/blob/master/fuzzy.c#L383
+func (p Params) initBlockSize(u uint64) uint32 {
+	bs := uint64(p.BlockSizeMin)
+	for bs*uint64(p.SigLength) < u && 4*bs <= math.MaxUint32 {
+		bs *= 2
+	}
+
+	return uint32(bs)
+}
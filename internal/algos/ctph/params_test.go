@@ -0,0 +1,176 @@
+package ctph
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func stepAll(t *testing.T, ctph *Ctph, data []byte, roundTrip bool) {
+	t.Helper()
+
+	for ctph.Retry {
+		var d byte
+		if i := ctph.Position(); i < len(data) {
+			d = data[i]
+		}
+
+		if roundTrip {
+			state := serialize(t, ctph)
+			*ctph = Ctph{}
+			if err := ctph.DeserializeState(state); err != nil {
+				t.Fatalf("failed to deserialize state: %v", err)
+			}
+		}
+		ctph.Step(d)
+	}
+}
+
+func TestInitWithOptions_WithDefaults_MatchesSsdeep(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/crowandthefox.txt")
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+
+	defaults, err := json.Marshal(DefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, params := range []string{"", "{}", string(defaults)} {
+		ctph := new(Ctph)
+		if err := ctph.InitWithOptions(len(data), json.RawMessage(params)); err != nil {
+			t.Fatalf("%q: unexpected error: %v", params, err)
+		}
+		stepAll(t, ctph, data, false)
+
+		if h := ctph.printSSDeep(); h != hash(t, data) {
+			t.Fatalf("%q: expected %s, got %s", params, hash(t, data), h)
+		}
+	}
+}
+
+func TestInitWithOptions_WithNonDefaultParams_RoundTripsThroughSerialization(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/mobydick.txt")
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+
+	params := Params{WindowSize: 11, BlockSizeMin: 5, SigLength: 32, Alphabet: "0123456789abcdef"}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := new(Ctph)
+	if err := expected.InitWithOptions(len(data), raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stepAll(t, expected, data, false)
+
+	ctph := new(Ctph)
+	if err := ctph.InitWithOptions(len(data), raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stepAll(t, ctph, data, true)
+
+	if !cmp.Equal(params, ctph.Params) {
+		t.Fatalf("params were not persisted: %s", cmp.Diff(params, ctph.Params))
+	}
+	if h := ctph.printSSDeep(); h != expected.printSSDeep() {
+		t.Fatalf("expected %s, got %s", expected.printSSDeep(), h)
+	}
+
+	// the signature only uses the alphabet, fills no more than the signature
+	// length and differs from ssdeep's
+	if h := ctph.printSSDeep(); h == hash(t, data) {
+		t.Fatalf("expected the params to change the hash, got %s", h)
+	}
+	if len(ctph.Sig1) > 32 || len(ctph.Sig2) > 16 || strings.Trim(ctph.Sig1+ctph.Sig2, params.Alphabet) != "" {
+		t.Fatalf("signature %s does not fit the params", ctph.printSSDeep())
+	}
+	if ctph.Bs%params.BlockSizeMin != 0 || len(ctph.Rh.Window) != 11 {
+		t.Fatalf("expected a multiple of 5 block size and an 11 byte window, got %d and %d", ctph.Bs, len(ctph.Rh.Window))
+	}
+}
+
+func TestInitWithOptions_WithLargestBlockSizeMinAndInput_DoesNotOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		inputLen int
+		expected uint32
+	}{
+		{1 << 20, 1 << 20},
+		{math.MaxUint32, 1 << 30},
+		// doubling stops before twice the block size overflows
+		{1 << 36, 1 << 30},
+	} {
+		ctph := new(Ctph)
+		if err := ctph.InitWithOptions(tc.inputLen, json.RawMessage(`{"block_size_min": 1048576, "signature_length": 4}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		if ctph.Bs != tc.expected {
+			t.Fatalf("%d: expected block size %d, got %d", tc.inputLen, tc.expected, ctph.Bs)
+		}
+		if err := ctph.Step('a'); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestInitWithOptions_WithInvalidParams_ReturnsError(t *testing.T) {
+	for _, params := range []string{
+		`{"window_size": 0}`,
+		`{"window_size": 65}`,
+		`{"block_size_min": 0}`,
+		`{"signature_length": 2}`,
+		`{"signature_length": 33}`,
+		`{"alphabet": "A"}`,
+		`{"alphabet": "AA"}`,
+		`{"alphabet": "AB:"}`,
+		`{"alphabet": "AB "}`,
+		`{"rolling_window": 7}`,
+		`{"window_size": "7"}`,
+	} {
+		if err := new(Ctph).InitWithOptions(10, json.RawMessage(params)); err == nil {
+			t.Errorf("%s: expected an error", params)
+		}
+	}
+}
+
+func TestDeserializeState_WithoutParams_UsesDefaults(t *testing.T) {
+	state := `{"block_size":3,"index":-1,"input_length":10,"is_trigger1":false,"is_trigger2":false,"retry":true,"rolling_hash":{"x":0,"y":0,"z":0,"c":0,"size":7,"window":[0,0,0,0,0,0,0]},"sig1":"","sig2":""}`
+
+	ctph := new(Ctph)
+	if err := ctph.DeserializeState(state); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cmp.Equal(DefaultParams(), ctph.Params) {
+		t.Fatalf("expected the default params: %s", cmp.Diff(DefaultParams(), ctph.Params))
+	}
+}
+
+func TestOptionsSchema_DefaultsAreTheDefaultParams(t *testing.T) {
+	defaults := map[string]interface{}{}
+	for _, o := range new(Ctph).OptionsSchema().Options {
+		defaults[o.Name] = o.Default
+	}
+
+	raw, err := json.Marshal(defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctph := new(Ctph)
+	if err := ctph.InitWithOptions(10, raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmp.Equal(DefaultParams(), ctph.Params) {
+		t.Fatalf("expected the default params: %s", cmp.Diff(DefaultParams(), ctph.Params))
+	}
+}
@@ -0,0 +1,141 @@
+package ctph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/joekir/algoexplore"
+)
+
+// The rolling hash of SSDEEP, based on the Adler-32 checksum:
+// https://github.com/ssdeep-project/ssdeep/blob/master/fuzzy.c#L142
+//
+// X is the sum of the bytes in the window, Y the sum weighted by how recently
+// each byte arrived and Z a shift-xor of the latest bytes, so that the sum of
+// the three depends only on the last few bytes of the input.
+
+func init() {
+	algoexplore.Register(func() algoexplore.AlgoPlugin { return &RollingHashAlgo{} })
+}
+
+// RollingHash - SubType of CTPH to maintain a rolling-window hash
+type RollingHash struct {
+	X      uint32   `json:"x"`
+	Y      uint32   `json:"y"`
+	Z      uint32   `json:"z"`
+	C      uint32   `json:"c"`
+	Size   uint32   `json:"size"`
+	Window []uint32 `json:"window"`
+}
+
+func newRollingHash(size uint32) *RollingHash {
+	return &RollingHash{
+		Size:   size,
+		Window: make([]uint32, size),
+	}
+}
+
+func (rh *RollingHash) hash(d byte) uint32 {
+	dint := uint32(d)
+	rh.Y = rh.Y - rh.X
+	rh.Y = rh.Y + rh.Size*dint
+	rh.X = rh.X + dint
+	rh.X = rh.X - rh.Window[rh.C%rh.Size]
+	rh.Window[rh.C%rh.Size] = dint
+	rh.C = (rh.C + 1) % rh.Size
+	rh.Z = rh.Z << 5
+	rh.Z = rh.Z ^ dint
+
+	return rh.sum()
+}
+
+func (rh *RollingHash) sum() uint32 {
+	return rh.X + rh.Y + rh.Z
+}
+
+// RollingHashAlgo - the rolling hash on its own, stepped a byte at a time
+// struct that contains the algorithm's state
+type RollingHashAlgo struct {
+	Index    int         `json:"index"`
+	InputLen int         `json:"input_length"`
+	Rh       RollingHash `json:"rolling_hash"`
+	Sum      uint32      `json:"sum"`
+}
+
+func (r *RollingHashAlgo) Name() string {
+	return "rollinghash"
+}
+
+// Describe - see algoexplore.Describer interface
+func (r *RollingHashAlgo) Describe() algoexplore.AlgoInfo {
+	return algoexplore.AlgoInfo{
+		DisplayName: "ssdeep rolling hash",
+		Version:     "1",
+		Description: "The rolling hash over the last 7 bytes that decides where ssdeep cuts its pieces",
+		Reference:   "assets/Kornblum_Identifying_almost_identical_files_using_context_triggered_piecewise_hashing.pdf",
+		Example:     "The quick brown fox jumped over the lazy dog's back",
+	}
+}
+
+// StateSchema - see algoexplore.StateDescriber interface
+func (r *RollingHashAlgo) StateSchema() algoexplore.StateSchema {
+	return algoexplore.StateSchema{Fields: []algoexplore.FieldSchema{
+		{Path: "rolling_hash.window", Label: "Window Array (hex)", Kind: algoexplore.KindByteArray, Bits: 8, Order: 0},
+		{Path: "rolling_hash.z", Label: "Z Register", Kind: algoexplore.KindRegister, Bits: 32, Order: 1},
+		{Path: "rolling_hash.x", Label: "X Value", Kind: algoexplore.KindScalar, Bits: 32, Order: 2},
+		{Path: "rolling_hash.y", Label: "Y Value", Kind: algoexplore.KindScalar, Bits: 32, Order: 3},
+		{Path: "sum", Label: "Rolling Hash", Kind: algoexplore.KindOutput, Order: 4},
+	}}
+}
+
+// Init - see algoexplore.AlgoWorker interface
+func (r *RollingHashAlgo) Init(InputLen int) error {
+	if InputLen < 1 {
+		return errors.New("invalid input length")
+	}
+
+	r.Index = -1
+	r.InputLen = InputLen
+	r.Rh = *newRollingHash(windowSize)
+	r.Sum = 0
+	return nil
+}
+
+// Step - see algoexplore.AlgoWorker interface
+func (r *RollingHashAlgo) Step(d byte) error {
+	if r.Done() {
+		return errors.New("the hash is done, it takes no more steps")
+	}
+	r.Index++
+	r.Sum = r.Rh.hash(d)
+	return nil
+}
+
+// Done - see algoexplore.AlgoWorker interface
+func (r *RollingHashAlgo) Done() bool {
+	return r.InputLen > 0 && r.Index+1 >= r.InputLen
+}
+
+// Output - see algoexplore.AlgoWorker interface
+func (r *RollingHashAlgo) Output() (string, error) {
+	return fmt.Sprintf("%08x", r.Sum), nil
+}
+
+// SerializeState - see algoexplore.AlgoWorker interface
+func (r *RollingHashAlgo) SerializeState() (string, error) {
+	byteArray, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return string(byteArray), nil
+}
+
+// DeserializeState - see algoexplore.AlgoWorker interface
+func (r *RollingHashAlgo) DeserializeState(state string) error {
+	var rd io.Reader
+	rd = strings.NewReader(state)
+	return algoexplore.StrictUnmarshalJSON(&rd, &r)
+}
@@ -0,0 +1,32 @@
+package ctph
+
+import (
+	"testing"
+)
+
+func TestRollingHashAlgo_Stepped_MatchesTheCtphRollingHash(t *testing.T) {
+	data := []byte("The quick brown fox jumped over the lazy dog's back")
+
+	r := new(RollingHashAlgo)
+	r.Init(len(data))
+	ctph := new(Ctph)
+	ctph.Init(len(data))
+
+	for _, b := range data {
+		state := serialize(t, r)
+		r = new(RollingHashAlgo)
+		if err := r.DeserializeState(state); err != nil {
+			t.Fatalf("failed to deserialize state: %v", err)
+		}
+
+		r.Step(b)
+		ctph.Step(b)
+		if r.Sum != ctph.Rh.sum() {
+			t.Fatalf("byte %d: expected a rolling hash of %d, got %d", r.Index, ctph.Rh.sum(), r.Sum)
+		}
+	}
+
+	if r.Index != len(data)-1 {
+		t.Fatalf("expected index %d, got %d", len(data)-1, r.Index)
+	}
+}
@@ -0,0 +1,285 @@
+package ctph
+
+// Similarity scoring of CTPH signatures, following fuzzy_compare from SSDEEP:
+// https://github.com/ssdeep-project/ssdeep/blob/master/fuzzy.c#L840
+//
+// Two signatures can only be compared when they share a block size, either
+// because both were hashed at the same one or because one block size is double
+// the other, in which case sig2 of the smaller is compared with sig1 of the larger.
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+var ssSigRegexp = regexp.MustCompile(ssSigPattern)
+
+// Score rates the similarity of 2 CTPH Signatures (s1 and s2) of the form:
+//
+//	24:O7XC9FZ2LBfaW3h+XdcDljuQJtNMMqF5DjQuwM0OHC:O7S9FZ2LwWEdcM6tNMjDEuwwHC
+//	<blocksize>:<sigpart1>:<sigpart2>[,"filename"]
+//
+// from 0 (no match) to 100 (identical), matching the score of the ssdeep tool.
+func Score(s1, s2 string) (int, error) {
+	c, err := Explain(s1, s2)
+	if err != nil {
+		return -1, err
+	}
+
+	return c.Score, nil
+}
+
+// Comparison - the Score of 2 signatures and the parts of them it was based on
+type Comparison struct {
+	Score int        `json:"score"`
+	Pairs []PartPair `json:"pairs"`
+}
+
+// PartPair - a part of each signature hashed at the same block size, Parts
+// names which part (1 or 2) was taken from each, and Ops aligns the two
+type PartPair struct {
+	BlockSize uint64   `json:"block_size"`
+	Parts     [2]int   `json:"parts"`
+	Score     int      `json:"score"`
+	Ops       []EditOp `json:"ops"`
+}
+
+// Edit operations, weighted as in editDistance
+const (
+	OpMatch   = "match"
+	OpReplace = "replace"
+	OpDelete  = "delete" // only in the first signature
+	OpInsert  = "insert" // only in the second signature
+)
+
+// EditOp - one step of an alignment, A and B index the characters of the
+// signature parts, either is -1 if the step has no character on that side.
+// Characters dropped by eliminateSequences are not aligned
+type EditOp struct {
+	Op string `json:"op"`
+	A  int    `json:"a"`
+	B  int    `json:"b"`
+}
+
+// Explain scores 2 signatures like Score, also returning the signature parts
+// that were compared and how they align
+func Explain(s1, s2 string) (*Comparison, error) {
+	bs1, s1b1, s1b2, ok := parseSignature(s1)
+	if !ok {
+		return nil, errors.New("invalid pattern in string 1")
+	}
+
+	bs2, s2b1, s2b2, ok := parseSignature(s2)
+	if !ok {
+		return nil, errors.New("invalid pattern in string 2")
+	}
+
+	c := &Comparison{Pairs: []PartPair{}}
+	switch {
+	case bs1 == bs2:
+		c.Pairs = append(c.Pairs, comparePair(s1b1, s2b1, bs1, [2]int{1, 1}),
+			comparePair(s1b2, s2b2, bs1*2, [2]int{2, 2}))
+	case bs1 == bs2*2:
+		c.Pairs = append(c.Pairs, comparePair(s1b1, s2b2, bs1, [2]int{1, 2}))
+	case bs2 == bs1*2:
+		c.Pairs = append(c.Pairs, comparePair(s1b2, s2b1, bs2, [2]int{2, 1}))
+	default:
+		return c, nil
+	}
+
+	for _, p := range c.Pairs {
+		if p.Score > c.Score {
+			c.Score = p.Score
+		}
+	}
+	if bs1 == bs2 && eliminateSequences(s1b1) == eliminateSequences(s2b1) {
+		c.Score = 100
+	}
+
+	return c, nil
+}
+
+func comparePair(p1, p2 string, bs uint64, parts [2]int) PartPair {
+	e1, e2 := eliminateSequences(p1), eliminateSequences(p2)
+	return PartPair{
+		BlockSize: bs,
+		Parts:     parts,
+		Score:     scoreStrings(e1, e2, bs),
+		Ops:       editOps(e1, e2, keptIndices(p1), keptIndices(p2)),
+	}
+}
+
+func parseSignature(s string) (uint64, string, string, bool) {
+	m := ssSigRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return 0, "", "", false
+	}
+
+	bs, err := strconv.ParseUint(m[1], 10, 32)
+	if err != nil {
+		return 0, "", "", false
+	}
+
+	return bs, m[2], m[3], true
+}
+
+// Runs of more than 3 identical characters carry little information,
+// so they are cut down to 3 before comparing
+func eliminateSequences(s string) string {
+	r := make([]byte, 0, len(s))
+	for _, i := range keptIndices(s) {
+		r = append(r, s[i])
+	}
+
+	return string(r)
+}
+
+// keptIndices lists the indices of s that eliminateSequences keeps
+func keptIndices(s string) []int {
+	kept := make([]int, 0, len(s))
+	for i := range s {
+		if i >= 3 && s[i] == s[i-1] && s[i] == s[i-2] && s[i] == s[i-3] {
+			continue
+		}
+		kept = append(kept, i)
+	}
+
+	return kept
+}
+
+// Implementation based on https://github.com/ssdeep-project/ssdeep/blob/master/fuzzy.c#L765
+func scoreStrings(s1, s2 string, bs uint64) int {
+	if uint32(len(s1)) > ssLength || uint32(len(s2)) > ssLength {
+		return 0
+	}
+
+	// signatures must share at least one full rolling window to be related
+	if !hasCommonSubstring(s1, s2) {
+		return 0
+	}
+
+	score := editDistance(s1, s2)
+	score = (score * int(ssLength)) / (len(s1) + len(s2))
+	score = (100 * score) / int(ssLength)
+	if score >= 100 {
+		return 0
+	}
+	score = 100 - score
+
+	// small block sizes can't vouch for a high score, so it is capped
+	// by how much of the input the shorter signature actually covers
+	if bs >= uint64((99+windowSize)/windowSize*blockSizeMin) {
+		return score
+	}
+	shortest := len(s1)
+	if len(s2) < shortest {
+		shortest = len(s2)
+	}
+	if limit := int(bs) / int(blockSizeMin) * shortest; score > limit {
+		return limit
+	}
+
+	return score
+}
+
+func hasCommonSubstring(s1, s2 string) bool {
+	n := int(windowSize)
+	if len(s1) < n || len(s2) < n {
+		return false
+	}
+
+	windows := make(map[string]bool, len(s1)-n+1)
+	for i := 0; i+n <= len(s1); i++ {
+		windows[s1[i:i+n]] = true
+	}
+	for i := 0; i+n <= len(s2); i++ {
+		if windows[s2[i:i+n]] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Edit distance as weighted by SSDEEP, an insertion or deletion costs 1
+// and a substitution costs 2 (the same as deleting then inserting)
+// https://github.com/ssdeep-project/ssdeep/blob/master/edit_dist.c
+func editDistance(s1, s2 string) int {
+	row := make([]int, len(s2)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(s1); i++ {
+		diag := row[0]
+		row[0] = i
+		for j := 1; j <= len(s2); j++ {
+			cost := diag
+			if s1[i-1] != s2[j-1] {
+				cost += 2
+			}
+			if row[j]+1 < cost {
+				cost = row[j] + 1
+			}
+			if row[j-1]+1 < cost {
+				cost = row[j-1] + 1
+			}
+			diag, row[j] = row[j], cost
+		}
+	}
+
+	return row[len(s2)]
+}
+
+// editOps aligns s1 and s2 with the least editDistance, k1 and k2 map the
+// characters of each back to their index in the signature part
+func editOps(s1, s2 string, k1, k2 []int) []EditOp {
+	d := make([][]int, len(s1)+1)
+	for i := range d {
+		d[i] = make([]int, len(s2)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s1); i++ {
+		for j := 1; j <= len(s2); j++ {
+			cost := d[i-1][j-1]
+			if s1[i-1] != s2[j-1] {
+				cost += 2
+			}
+			if d[i-1][j]+1 < cost {
+				cost = d[i-1][j] + 1
+			}
+			if d[i][j-1]+1 < cost {
+				cost = d[i][j-1] + 1
+			}
+			d[i][j] = cost
+		}
+	}
+
+	// walk back from the end, preferring to pair characters up
+	ops := []EditOp{}
+	for i, j := len(s1), len(s2); i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && s1[i-1] == s2[j-1] && d[i][j] == d[i-1][j-1]:
+			i, j = i-1, j-1
+			ops = append(ops, EditOp{OpMatch, k1[i], k2[j]})
+		case i > 0 && j > 0 && d[i][j] == d[i-1][j-1]+2:
+			i, j = i-1, j-1
+			ops = append(ops, EditOp{OpReplace, k1[i], k2[j]})
+		case i > 0 && d[i][j] == d[i-1][j]+1:
+			i--
+			ops = append(ops, EditOp{OpDelete, k1[i], -1})
+		default:
+			j--
+			ops = append(ops, EditOp{OpInsert, -1, k2[j]})
+		}
+	}
+
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+	return ops
+}
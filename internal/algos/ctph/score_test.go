@@ -0,0 +1,176 @@
+package ctph
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScore_WithReferenceVectors_MatchesExistingTool(t *testing.T) {
+	// Comparison values generated with ssdeep tool
+	for _, tc := range []struct {
+		s1, s2   string
+		expected int
+	}{
+		{
+			"192:MUPMinqP6+wNQ7Q40L/iB3n2rIBrP0GZKF4jsef+0FVQLSwbLbj41iH8nFVYv980:x0CllivQiFmt",
+			"192:JkjRcePWsNVQza3ntZStn5VfsoXMhRD9+xJMinqF6+wNQ7Q40L/i737rPVt:JkjlQyIrx+kll2",
+			35,
+		},
+		{
+			"196608:pDSC8olnoL1v/uawvbQD7XlZUFYzYyMb615NktYHF7dREN/JNnQrmhnUPI+/n2Yr:5DHoJXv7XOq7Mb2TwYHXREN/3QrmktPd",
+			"196608:7DSC8olnoL1v/uawvbQD7XlZUFYzYyMb615NktYHF7dREN/JNnQrmhnUPI+/n2Y7:3DHoJXv7XOq7Mb2TwYHXREN/3QrmktPt",
+			97,
+		},
+		{
+			"24:YDVLfsT1ds/1H9Wpgq7n4XMijV6h4Z3QCw4qat:YD51H9CiMuV6uACwVat",
+			"24:YDVLfyvDj+C+opg8DV0Mdle6hPZ3QCw4qat:YDMvDj+C+kBOM+6HACwVat",
+			54,
+		},
+	} {
+		score, err := Score(tc.s1, tc.s2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if score != tc.expected {
+			t.Fatalf("expected %d, got %d for %s and %s", tc.expected, score, tc.s1, tc.s2)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		s1, s2   string
+		expected int
+	}{
+		{"identical", "48:O7XC9FZ2LBfaW3h:O7S9FZ2L", "48:O7XC9FZ2LBfaW3h:O7S9FZ2L", 100},
+		{"identical with filenames", `48:O7XC9FZ2LBfaW3h:O7S9FZ2L,"a.txt"`, `48:O7XC9FZ2LBfaW3h:O7S9FZ2L,"b.txt"`, 100},
+		{"no common substring", "48:O7XC9FZ2LBfaW3h:O7S9FZ2L", "48:O7XC9FqqLBfaWqq:O7S9FqqL", 0},
+		{"no substitutions", "48:ABCDEFGH:A", "48:ABCDEFGX:B", 88},
+		{"double block size", "96:O7XC9FZ2LBfaW3h:AAAA", "48:BBBB:O7XC9FZ2LBfaW3h", 100},
+		{"half block size", "48:BBBB:O7XC9FZ2LBfaW3h", "96:O7XC9FZ2LBfaW3h:AAAA", 100},
+		{"incompatible block size", "24:O7XC9FZ2LBfaW3h:O7S9FZ2L", "96:O7XC9FZ2LBfaW3h:O7S9FZ2L", 0},
+		{"sequences eliminated", "48:AAAAAAAAABCDEFG:A", "48:AAAABCDEFG:B", 100},
+		{"small block size capped", "3:ABCDEFGH:A", "3:ABCDEFGX:B", 8},
+		{"empty", "3::", "3::", 100},
+	} {
+		score, err := Score(tc.s1, tc.s2)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if score != tc.expected {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.expected, score)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		s1, s2   string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"abc", "abc", 0},
+		{"abc", "abd", 2},
+		{"abc", "abcd", 1},
+		{"kitten", "sitting", 5},
+	} {
+		if d := editDistance(tc.s1, tc.s2); d != tc.expected {
+			t.Fatalf("expected %d, got %d for %q and %q", tc.expected, d, tc.s1, tc.s2)
+		}
+	}
+}
+
+func TestEliminateSequences(t *testing.T) {
+	for _, tc := range []struct {
+		in, expected string
+	}{
+		{"", ""},
+		{"AAA", "AAA"},
+		{"AAAA", "AAA"},
+		{"AAAAAAB", "AAAB"},
+		{"ABBBBBCCCCD", "ABBBCCCD"},
+	} {
+		if s := eliminateSequences(tc.in); s != tc.expected {
+			t.Fatalf("expected %s, got %s", tc.expected, s)
+		}
+	}
+}
+
+func TestExplain_WithSameBlockSize_AlignsBothParts(t *testing.T) {
+	c, err := Explain("48:ABCDEFGH:ABCDEFGHIJ", "48:ABCDEFGX:ABCDEFGHXIJ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(c.Pairs) != 2 || c.Pairs[0].Parts != [2]int{1, 1} || c.Pairs[1].Parts != [2]int{2, 2} {
+		t.Fatalf("unexpected pairs: %+v", c.Pairs)
+	}
+	if c.Pairs[1].BlockSize != 96 {
+		t.Fatalf("expected the second parts at block size 96, got %d", c.Pairs[1].BlockSize)
+	}
+	if c.Score != c.Pairs[1].Score || c.Score <= c.Pairs[0].Score {
+		t.Fatalf("expected the best pair's score, got %d for %+v", c.Score, c.Pairs)
+	}
+
+	expected := []EditOp{
+		{OpMatch, 0, 0}, {OpMatch, 1, 1}, {OpMatch, 2, 2}, {OpMatch, 3, 3}, {OpMatch, 4, 4},
+		{OpMatch, 5, 5}, {OpMatch, 6, 6}, {OpMatch, 7, 7}, {OpInsert, -1, 8}, {OpMatch, 8, 9}, {OpMatch, 9, 10},
+	}
+	if !cmp.Equal(expected, c.Pairs[1].Ops) {
+		t.Fatalf("unexpected ops: %s", cmp.Diff(expected, c.Pairs[1].Ops))
+	}
+}
+
+func TestExplain_WithAdjacentBlockSizes_AlignsTheSharedPart(t *testing.T) {
+	c, err := Explain("24:BBBB:O7XC9FZ2", "48:O7XC9FZ2:AAAA")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(c.Pairs) != 1 || c.Pairs[0].Parts != [2]int{2, 1} || c.Pairs[0].BlockSize != 48 || c.Score != 100 {
+		t.Fatalf("unexpected comparison: %+v", c)
+	}
+}
+
+func TestExplain_WithSequences_IndexesTheOriginalCharacters(t *testing.T) {
+	c, err := Explain("48:AAAAAAB:A", "48:AAAB:A")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []EditOp{{OpMatch, 0, 0}, {OpMatch, 1, 1}, {OpMatch, 2, 2}, {OpMatch, 6, 3}}
+	if c.Score != 100 || !cmp.Equal(expected, c.Pairs[0].Ops) {
+		t.Fatalf("unexpected comparison: %+v", c)
+	}
+}
+
+func TestEditOps_CostTheEditDistance(t *testing.T) {
+	weights := map[string]int{OpMatch: 0, OpReplace: 2, OpDelete: 1, OpInsert: 1}
+	for _, tc := range [][2]string{
+		{"", ""}, {"abc", ""}, {"", "abc"}, {"abc", "abd"}, {"kitten", "sitting"},
+		{"O7XC9FZ2LBfaW3h+XdcDljuQJtNMMqF5DjQuwM0OHC", "O7XC9FZ2LBfaW3hXdcDljuQJtNxMqF5DjQuwM0OHCz"},
+	} {
+		ops := editOps(tc[0], tc[1], keptIndices(tc[0]), keptIndices(tc[1]))
+		cost, a, b := 0, "", ""
+		for _, op := range ops {
+			cost += weights[op.Op]
+			if op.A >= 0 {
+				a += string(tc[0][op.A])
+			}
+			if op.B >= 0 {
+				b += string(tc[1][op.B])
+			}
+		}
+
+		if a != tc[0] || b != tc[1] {
+			t.Fatalf("ops do not cover %q and %q: %+v", tc[0], tc[1], ops)
+		}
+		if d := editDistance(tc[0], tc[1]); cost != d {
+			t.Fatalf("expected ops costing %d, got %d for %q and %q", d, cost, tc[0], tc[1])
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package ctph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/joekir/algoexplore"
+)
+
+// Single pass CTPH, following the engine of SSDEEP 2.13 onwards:
+// https://github.com/ssdeep-project/ssdeep/blob/master/fuzzy.c#L210
+//
+// Rather than guessing a block size and retrying at half of it, the pieces of
+// every candidate block size are hashed at once, each in its own lane. A lane
+// is started the first time the lane below it triggers, and the lanes too
+// small to be picked are dropped once the lane above has enough characters.
+
+const (
+	maxLanes = 31
+
+	// the largest input ssdeep will hash
+	maxStreamLength = uint64(blockSizeMin) << (maxLanes - 1) * uint64(ssLength)
+)
+
+func init() {
+	algoexplore.Register(func() algoexplore.AlgoPlugin { return &Stream{} })
+}
+
+// Lane - the piece hashes of one candidate block size
+// Tail is the character from the last trigger once Digest is full,
+// HalfHash and HalfTail do the same once Digest has half its characters
+type Lane struct {
+	Bs       uint32 `json:"block_size"`
+	Hash     Sum32  `json:"hash"`
+	HalfHash Sum32  `json:"half_hash"`
+	Digest   string `json:"digest"`
+	Tail     string `json:"tail"`
+	HalfTail string `json:"half_tail"`
+}
+
+// Stream - single pass Context Triggered Piecewise Hashing
+// struct that contains the algorithm's state
+type Stream struct {
+	Index    int         `json:"index"`
+	InputLen int         `json:"input_length"`
+	Rh       RollingHash `json:"rolling_hash"`
+	Lanes    []Lane      `json:"lanes"`
+	Start    int         `json:"start"`
+	LastHash *Sum32      `json:"last_hash"`
+	Triggers int         `json:"triggers"`
+
+	// derived when serialized, to show which lane would be picked if the
+	// input ended at the current byte
+	Winning    int    `json:"winning"`
+	IsTrigger1 bool   `json:"is_trigger1"`
+	IsTrigger2 bool   `json:"is_trigger2"`
+	Signature  string `json:"signature"`
+}
+
+// NewStream returns a Stream for an input of unknown length, ready to Write to
+func NewStream() *Stream {
+	s := new(Stream)
+	s.reset()
+	return s
+}
+
+func (s *Stream) Name() string {
+	return "ctph-stream"
+}
+
+// Describe - see algoexplore.Describer interface
+func (s *Stream) Describe() algoexplore.AlgoInfo {
+	return algoexplore.AlgoInfo{
+		DisplayName: "ssdeep (single pass)",
+		Version:     "1",
+		Description: "ssdeep hashing every candidate block size at once, as ssdeep 2.13 onwards does",
+		Reference:   "assets/Kornblum_Identifying_almost_identical_files_using_context_triggered_piecewise_hashing.pdf",
+		Example:     "The quick brown fox jumped over the lazy dog's back",
+	}
+}
+
+// StateSchema - see algoexplore.StateDescriber interface
+func (s *Stream) StateSchema() algoexplore.StateSchema {
+	return algoexplore.StateSchema{Fields: []algoexplore.FieldSchema{
+		{Path: "is_trigger1", Label: "ModBS", Kind: algoexplore.KindTrigger, Order: 0},
+		{Path: "is_trigger2", Label: "Mod2BS", Kind: algoexplore.KindTrigger, Order: 1},
+		{Path: "rolling_hash.window", Label: "Window Array (hex)", Kind: algoexplore.KindByteArray, Bits: 8, Order: 2},
+		{Path: "rolling_hash.x", Label: "X Value", Kind: algoexplore.KindScalar, Bits: 32, Order: 3},
+		{Path: "rolling_hash.y", Label: "Y Value", Kind: algoexplore.KindScalar, Bits: 32, Order: 4},
+		{Path: "rolling_hash.z", Label: "Z Value", Kind: algoexplore.KindScalar, Bits: 32, Order: 5},
+		{Path: "lanes", Label: "Block Size Lanes", Kind: algoexplore.KindTable,
+			Columns: []string{"block_size", "digest"}, Highlight: "winning", Order: 6},
+		{Path: "signature", Label: "Signature", Kind: algoexplore.KindOutput, Order: 7},
+	}}
+}
+
+// Init - see algoexplore.AlgoWorker interface
+func (s *Stream) Init(InputLen int) error {
+	if InputLen < 1 {
+		return errors.New("invalid input length")
+	}
+
+	s.reset()
+	s.InputLen = InputLen
+	return nil
+}
+
+// Step - see algoexplore.AlgoWorker interface
+func (s *Stream) Step(d byte) error {
+	if s.Done() {
+		return errors.New("the hash is done, it takes no more steps")
+	}
+	s.Index++
+	rs := s.Rh.hash(d)
+
+	for i := s.Start; i < len(s.Lanes); i++ {
+		s.Lanes[i].Hash.Write([]byte{d})
+		s.Lanes[i].HalfHash.Write([]byte{d})
+	}
+	if s.LastHash != nil {
+		s.LastHash.Write([]byte{d})
+	}
+
+	// block sizes double from lane to lane, so a lane can only trigger if all
+	// the lanes below it did
+	s.Triggers = 0
+	for i := s.Start; i < len(s.Lanes); i++ {
+		if rs%s.Lanes[i].Bs != s.Lanes[i].Bs-1 {
+			break
+		}
+		s.Triggers++
+
+		if len(s.Lanes[i].Digest) == 0 {
+			s.fork()
+		}
+
+		l := &s.Lanes[i]
+		c := string(b64Chars[l.Hash.Sum32()&0x3F])
+		l.HalfTail = string(b64Chars[l.HalfHash.Sum32()&0x3F])
+		if uint32(len(l.Digest)) < ssLength-1 {
+			l.Digest += c
+			l.Hash.Reset()
+			if uint32(len(l.Digest)) < ssLength/2 {
+				l.HalfHash.Reset()
+				l.HalfTail = ""
+			}
+		} else {
+			l.Tail = c
+			s.reduce()
+		}
+	}
+	return nil
+}
+
+// Write steps through every byte of p, see io.Writer
+func (s *Stream) Write(p []byte) (int, error) {
+	if uint64(s.Index+1)+uint64(len(p)) > maxStreamLength {
+		return 0, errors.New("input too large for ssdeep")
+	}
+
+	for i, d := range p {
+		if err := s.Step(d); err != nil {
+			return i, err
+		}
+	}
+	return len(p), nil
+}
+
+// Sum returns the ssdeep signature of the input written so far
+func (s *Stream) Sum() string {
+	sig, _ := s.signature()
+	return sig
+}
+
+// Done - see algoexplore.AlgoWorker interface
+// A Stream written to without a known length is never done
+func (s *Stream) Done() bool {
+	return s.InputLen > 0 && s.Index+1 >= s.InputLen
+}
+
+// Output - see algoexplore.AlgoWorker interface
+func (s *Stream) Output() (string, error) {
+	return s.Sum(), nil
+}
+
+// SerializeState - see algoexplore.AlgoWorker interface
+func (s *Stream) SerializeState() (string, error) {
+	s.Signature, s.Winning = s.signature()
+	s.IsTrigger1 = s.Winning < s.Start+s.Triggers
+	s.IsTrigger2 = s.Winning+1 < s.Start+s.Triggers
+
+	byteArray, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return string(byteArray), nil
+}
+
+// DeserializeState - see algoexplore.AlgoWorker interface
+func (s *Stream) DeserializeState(state string) error {
+	var r io.Reader
+	r = strings.NewReader(state)
+	return algoexplore.StrictUnmarshalJSON(&r, &s)
+}
+
+// signature picks the lane ssdeep would use for the input so far, from the
+// initial guess down to the first lane with at least half its characters,
+// and returns the signature along with that lane's index
+func (s *Stream) signature() (string, int) {
+	total := uint64(s.Index + 1)
+	bi := s.Start
+	for uint64(blockSizeMin<<uint(bi))*uint64(ssLength) < total {
+		bi++
+	}
+	if bi >= len(s.Lanes) {
+		bi = len(s.Lanes) - 1
+	}
+	for bi > s.Start && uint32(len(s.Lanes[bi].Digest)) < ssLength/2 {
+		bi--
+	}
+
+	// as with Ctph, what is left in the piece hashes is only digested when
+	// the rolling hash at the last byte is non-zero
+	digested := s.Rh.sum() != 0
+	l := s.Lanes[bi]
+	sig1 := l.Digest + l.Tail
+	if digested {
+		sig1 = l.Digest + string(b64Chars[l.Hash.Sum32()&0x3F])
+	}
+
+	var sig2 string
+	switch {
+	case bi < len(s.Lanes)-1:
+		next := s.Lanes[bi+1]
+		sig2 = next.Digest
+		if uint32(len(sig2)) > ssLength/2-1 {
+			sig2 = sig2[:ssLength/2-1]
+		}
+		if digested {
+			sig2 += string(b64Chars[next.HalfHash.Sum32()&0x3F])
+		} else {
+			sig2 += next.HalfTail
+		}
+	case digested && s.LastHash != nil:
+		sig2 = string(b64Chars[s.LastHash.Sum32()&0x3F])
+	case digested:
+		sig2 = string(b64Chars[l.Hash.Sum32()&0x3F])
+	}
+
+	return fmt.Sprintf("%d:%s:%s", l.Bs, sig1, sig2), bi
+}
+
+// fork starts the lane above the last, its pieces so far are the same as the
+// last lane's, as that lane has only just triggered for the first time
+func (s *Stream) fork() {
+	last := s.Lanes[len(s.Lanes)-1]
+	if len(s.Lanes) < s.laneLimit() {
+		s.Lanes = append(s.Lanes, Lane{Bs: last.Bs * 2, Hash: last.Hash, HalfHash: last.HalfHash})
+	} else if len(s.Lanes) == maxLanes && s.LastHash == nil {
+		h := last.Hash
+		s.LastHash = &h
+	}
+}
+
+// reduce drops the smallest lane once it can no longer be picked
+func (s *Stream) reduce() {
+	if len(s.Lanes)-s.Start < 2 {
+		return
+	}
+
+	if uint64(s.Lanes[s.Start].Bs)*uint64(ssLength) >= s.expectedLength() {
+		return
+	}
+
+	if uint32(len(s.Lanes[s.Start+1].Digest)) < ssLength/2 {
+		return
+	}
+
+	s.Start++
+}
+
+// laneLimit - when the input length is known, no lane beyond the one above
+// the initial guess can be picked, so none are started
+func (s *Stream) laneLimit() int {
+	if s.InputLen < 1 {
+		return maxLanes
+	}
+
+	bi := 0
+	for uint64(blockSizeMin<<uint(bi))*uint64(ssLength) < uint64(s.InputLen) {
+		bi++
+	}
+	if bi+2 < maxLanes {
+		return bi + 2
+	}
+	return maxLanes
+}
+
+func (s *Stream) expectedLength() uint64 {
+	if s.InputLen > 0 {
+		return uint64(s.InputLen)
+	}
+	return uint64(s.Index + 1)
+}
+
+func (s *Stream) reset() {
+	s.Index = -1
+	s.InputLen = 0
+	s.Rh = *newRollingHash(windowSize)
+	s.Lanes = []Lane{{Bs: blockSizeMin, Hash: *NewFNV(), HalfHash: *NewFNV()}}
+	s.Start = 0
+	s.LastHash = nil
+	s.Triggers = 0
+}
@@ -0,0 +1,71 @@
+package ctph
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestStream_WrittenInPieces_MatchesTheRetryingEngine(t *testing.T) {
+	data := make([]byte, 300000)
+	rand.New(rand.NewSource(3)).Read(data)
+
+	s := NewStream()
+	for rest, n := data, 1; len(rest) > 0; n *= 3 {
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if _, err := s.Write(rest[:n]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rest = rest[n:]
+	}
+
+	if expected, _, err := HashChunks(data); err != nil || s.Sum() != expected {
+		t.Fatalf("expected %s (%v), got %s", expected, err, s.Sum())
+	}
+}
+
+func TestStream_SteppedAsPlugin_ShowsTheWinningLane(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/mobydick.txt")
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+
+	s := new(Stream)
+	s.Init(len(data))
+	for _, b := range data {
+		state := serialize(t, s)
+		s = new(Stream)
+		if err := s.DeserializeState(state); err != nil {
+			t.Fatalf("failed to deserialize state: %v", err)
+		}
+		s.Step(b)
+	}
+	s.SerializeState()
+
+	if expected := hash(t, data); s.Signature != expected {
+		t.Fatalf("expected %s, got %s", expected, s.Signature)
+	}
+	if bs := s.Lanes[s.Winning].Bs; !strings.HasPrefix(s.Signature, "384:") || bs != 384 {
+		t.Fatalf("expected the 384 lane to win, got %d", bs)
+	}
+	if len(s.Lanes) > s.laneLimit() {
+		t.Fatalf("expected at most %d lanes, got %d", s.laneLimit(), len(s.Lanes))
+	}
+}
+
+func TestStream_WithEmptyInput_ReturnsEmptySignature(t *testing.T) {
+	if h := NewStream().Sum(); h != "3::" {
+		t.Fatalf("Unexpected hash: %s", h)
+	}
+}
+
+func TestStream_PastTheLargestInput_ReturnsError(t *testing.T) {
+	s := NewStream()
+	s.Index = int(maxStreamLength) - 1
+	if _, err := s.Write([]byte{1}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,145 @@
+package ctph
+
+import (
+	"flag"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const vectorsFile = "testdata/vectors.txt"
+
+var ssdeepBin = flag.String("ctph.ssdeep", "",
+	"regenerate "+vectorsFile+" with this ssdeep 2.14 binary rather than checking the vectors")
+
+// vector - a line of the test vectors, the input it generates and the hash
+// ssdeep gives it
+type vector struct {
+	line     int
+	gen      string
+	param    string
+	data     []byte
+	expected string
+}
+
+func (v vector) String() string {
+	return strings.Join([]string{v.gen, v.param, strconv.Itoa(len(v.data))}, " ")
+}
+
+// readVectors returns every line of the test vectors file, and the vectors
+// parsed from them
+func readVectors(t *testing.T) ([]string, []vector) {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(vectorsFile)
+	if err != nil {
+		t.Fatalf("could not read test vectors: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	var vectors []vector
+	for i, line := range lines {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		f := strings.Split(line, "\t")
+		if len(f) != 4 {
+			t.Fatalf("malformed test vector: %s", line)
+		}
+		size, err := strconv.Atoi(f[2])
+		if err != nil {
+			t.Fatalf("malformed test vector size: %s", line)
+		}
+		vectors = append(vectors, vector{line: i, gen: f[0], param: f[1],
+			data: generate(t, f[0], f[1], size), expected: f[3]})
+	}
+	return lines, vectors
+}
+
+// generate returns the size bytes of input the generator gen makes from param
+func generate(t *testing.T, gen, param string, size int) []byte {
+	t.Helper()
+
+	data := make([]byte, size)
+	switch gen {
+	case "random":
+		p := strings.SplitN(param+"@0", "@", 3)
+		seed, err := strconv.ParseInt(p[0], 10, 64)
+		if err != nil {
+			t.Fatalf("malformed test vector seed: %s", param)
+		}
+		skip, err := strconv.Atoi(p[1])
+		if err != nil {
+			t.Fatalf("malformed test vector skip: %s", param)
+		}
+		r := rand.New(rand.NewSource(seed))
+		r.Read(make([]byte, skip))
+		r.Read(data)
+	case "fill":
+		b, err := strconv.ParseUint(param, 16, 8)
+		if err != nil {
+			t.Fatalf("malformed test vector byte: %s", param)
+		}
+		for i := range data {
+			data[i] = byte(b)
+		}
+	case "text":
+		text, err := strconv.Unquote(param)
+		if err != nil || len(text) != size {
+			t.Fatalf("malformed test vector text: %s", param)
+		}
+		copy(data, text)
+	default:
+		t.Fatalf("unknown test vector generator: %s", gen)
+	}
+	return data
+}
+
+// regenerateVectors rewrites the expected hash of every vector with the one
+// the ssdeep binary gives, keeping the rest of the file as it is
+func regenerateVectors(t *testing.T, lines []string, vectors []vector) {
+	out, err := exec.Command(*ssdeepBin, "-V").Output()
+	if err != nil {
+		t.Fatalf("could not run %s: %v", *ssdeepBin, err)
+	}
+	if version := strings.TrimSpace(string(out)); version != "2.14" {
+		t.Fatalf("the vectors are of ssdeep 2.14, %s is %s", *ssdeepBin, version)
+	}
+
+	dir, err := ioutil.TempDir("", "vectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	for _, v := range vectors {
+		if err := ioutil.WriteFile(input, v.data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		// -s silences errors, -b leaves the directory out of the file name
+		out, err := exec.Command(*ssdeepBin, "-s", "-b", input).Output()
+		if err != nil {
+			t.Fatalf("%s: %s failed: %v", v, *ssdeepBin, err)
+		}
+
+		// a header line, then the hash followed by the file name
+		result := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if len(result) != 2 || !strings.HasSuffix(result[1], `,"input"`) {
+			t.Fatalf("%s: unexpected ssdeep output %q", v, out)
+		}
+		h := strings.TrimSuffix(result[1], `,"input"`)
+		lines[v.line] = strings.Join([]string{v.gen, v.param, strconv.Itoa(len(v.data)), h}, "\t")
+	}
+
+	if err := ioutil.WriteFile(vectorsFile, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("regenerated %d vectors with %s", len(vectors), *ssdeepBin)
+}
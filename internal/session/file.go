@@ -0,0 +1,169 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore - keeps session data as one file per session in a directory,
+// files that have not been written to within the TTL are evicted
+type FileStore struct {
+	mu        sync.Mutex
+	dir       string
+	ttl       time.Duration
+	nextSweep time.Time
+	now       func() time.Time
+	ids       locks
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating it if needed
+func NewFileStore(dir string, ttl time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		dir: dir,
+		ttl: ttl,
+		now: time.Now,
+	}, nil
+}
+
+// Get - see Store interface
+func (f *FileStore) Get(id string) ([]byte, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := f.path(id)
+	info, err := os.Stat(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if f.expired(info, f.now()) {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+
+	return ioutil.ReadFile(p)
+}
+
+// Set - see Store interface
+func (f *FileStore) Set(id string, data []byte) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.sweep(f.now()); err != nil {
+		return err
+	}
+
+	// write then rename, so a crash never leaves a half written session
+	tmp, err := ioutil.TempFile(f.dir, id+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path(id))
+}
+
+// Delete - see Store interface
+func (f *FileStore) Delete(id string) error {
+	if !validID(id) {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.Remove(f.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Touch - see Store interface
+func (f *FileStore) Touch(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := f.path(id)
+	info, err := os.Stat(p)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	now := f.now()
+	if f.expired(info, now) {
+		return ErrNotFound
+	}
+	return os.Chtimes(p, now, now)
+}
+
+// Lock - see Store interface
+func (f *FileStore) Lock(id string) func() {
+	return f.ids.lock(id)
+}
+
+func (f *FileStore) path(id string) string {
+	return filepath.Join(f.dir, id)
+}
+
+func (f *FileStore) expired(info os.FileInfo, now time.Time) bool {
+	return now.After(info.ModTime().Add(f.ttl))
+}
+
+// sweep removes every expired session file, at most once per TTL period
+// the caller must hold the lock
+func (f *FileStore) sweep(now time.Time) error {
+	if now.Before(f.nextSweep) {
+		return nil
+	}
+
+	infos, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if !validID(info.Name()) || !f.expired(info, now) {
+			continue
+		}
+		if err := os.Remove(f.path(info.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	f.nextSweep = now.Add(f.ttl)
+	return nil
+}
@@ -0,0 +1,112 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestFileStore(t *testing.T) *FileStore {
+	dir, err := ioutil.TempDir("", "sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	f, err := NewFileStore(dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestFileStore_SetThenGet_ReturnsData(t *testing.T) {
+	f := newTestFileStore(t)
+	id, err := NewID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Set(id, []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := f.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "state" {
+		t.Fatalf("expected 'state', got %s", data)
+	}
+}
+
+func TestFileStore_afterTTL_EvictsFiles(t *testing.T) {
+	f := newTestFileStore(t)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	id, _ := NewID()
+	if err := f.Set(id, []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	other, _ := NewID()
+	if err := f.Set(other, []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(f.dir, id)); !os.IsNotExist(err) {
+		t.Fatalf("expected expired session file to be swept, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := f.Get(other); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFileStore_Touch_RestartsTheTTL(t *testing.T) {
+	f := newTestFileStore(t)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	id, _ := NewID()
+	if err := f.Touch(id); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := f.Set(id, []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(50 * time.Second)
+	if err := f.Touch(id); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(50 * time.Second)
+	if data, err := f.Get(id); err != nil || string(data) != "state" {
+		t.Fatalf("expected the touched file to live on, got %s %v", data, err)
+	}
+}
+
+func TestFileStore_withInvalidID_NeverTouchesDisk(t *testing.T) {
+	f := newTestFileStore(t)
+
+	if err := f.Set("../../etc/passwd", []byte("state")); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if _, err := f.Get("../../etc/passwd"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNew_withUnknownBackend_ReturnsError(t *testing.T) {
+	if _, err := New("redis", "", time.Minute); err == nil {
+		t.Fatal("expected an error for an unknown backend")
+	}
+}
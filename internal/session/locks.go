@@ -0,0 +1,40 @@
+package session
+
+import "sync"
+
+// locks - a mutex per session ID, each kept only while it is held or waited on
+type locks struct {
+	mu   sync.Mutex
+	byID map[string]*idLock
+}
+
+type idLock struct {
+	mu    sync.Mutex
+	users int
+}
+
+// lock holds the mutex of id until the returned func is called
+func (l *locks) lock(id string) (unlock func()) {
+	l.mu.Lock()
+	if l.byID == nil {
+		l.byID = map[string]*idLock{}
+	}
+	k, ok := l.byID[id]
+	if !ok {
+		k = &idLock{}
+		l.byID[id] = k
+	}
+	k.users++
+	l.mu.Unlock()
+
+	k.mu.Lock()
+	return func() {
+		k.mu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if k.users--; k.users == 0 {
+			delete(l.byID, id)
+		}
+	}
+}
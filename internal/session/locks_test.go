@@ -0,0 +1,36 @@
+package session
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLocks_lock_SerializesEachID(t *testing.T) {
+	var l locks
+	var wg sync.WaitGroup
+	// each ID has its own counter, so only holders of the same ID share one
+	counts := map[string]*int{"a": new(int), "b": new(int)}
+
+	for i := 0; i < 100; i++ {
+		for _, id := range []string{"a", "b"} {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				unlock := l.lock(id)
+				defer unlock()
+
+				// a lost update if another holder of id interleaved
+				n := *counts[id]
+				*counts[id] = n + 1
+			}(id)
+		}
+	}
+	wg.Wait()
+
+	if *counts["a"] != 100 || *counts["b"] != 100 {
+		t.Fatalf("expected 100 updates of each, got %d and %d", *counts["a"], *counts["b"])
+	}
+	if len(l.byID) != 0 {
+		t.Fatalf("expected no locks kept once released, got %d", len(l.byID))
+	}
+}
@@ -0,0 +1,104 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// MemoryStore - keeps session data in process memory, evicting entries that
+// have not been written to within the TTL
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	ttl       time.Duration
+	nextSweep time.Time
+	now       func() time.Time
+	ids       locks
+}
+
+// NewMemoryStore creates an empty MemoryStore with the given TTL
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		entries: map[string]memoryEntry{},
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// Get - see Store interface
+func (m *MemoryStore) Get(id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if m.now().After(e.expires) {
+		delete(m.entries, id)
+		return nil, ErrNotFound
+	}
+
+	return e.data, nil
+}
+
+// Set - see Store interface
+func (m *MemoryStore) Set(id string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+	m.entries[id] = memoryEntry{data: data, expires: now.Add(m.ttl)}
+	return nil
+}
+
+// Delete - see Store interface
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, id)
+	return nil
+}
+
+// Touch - see Store interface
+func (m *MemoryStore) Touch(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	e, ok := m.entries[id]
+	if !ok || now.After(e.expires) {
+		return ErrNotFound
+	}
+	e.expires = now.Add(m.ttl)
+	m.entries[id] = e
+	return nil
+}
+
+// Lock - see Store interface
+func (m *MemoryStore) Lock(id string) func() {
+	return m.ids.lock(id)
+}
+
+// sweep evicts every expired entry, at most once per TTL period
+// the caller must hold the lock
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Before(m.nextSweep) {
+		return
+	}
+
+	for id, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, id)
+		}
+	}
+	m.nextSweep = now.Add(m.ttl)
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_SetThenGet_ReturnsData(t *testing.T) {
+	m := NewMemoryStore(time.Minute)
+
+	if err := m.Set("abc", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := m.Get("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "state" {
+		t.Fatalf("expected 'state', got %s", data)
+	}
+}
+
+func TestMemoryStore_afterTTL_EvictsEntries(t *testing.T) {
+	now := time.Now()
+	m := NewMemoryStore(time.Minute)
+	m.now = func() time.Time { return now }
+
+	if err := m.Set("old", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := m.Get("old"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := m.Set("other", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Set("expired", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+
+	// writing any entry sweeps out the expired ones
+	if err := m.Set("new", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.entries) != 1 {
+		t.Fatalf("expected only 1 live entry, got %d", len(m.entries))
+	}
+}
+
+func TestMemoryStore_Delete_RemovesEntry(t *testing.T) {
+	m := NewMemoryStore(time.Minute)
+
+	if err := m.Set("abc", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Delete("abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Get("abc"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryStore_Touch_RestartsTheTTL(t *testing.T) {
+	now := time.Now()
+	m := NewMemoryStore(time.Minute)
+	m.now = func() time.Time { return now }
+
+	if err := m.Touch("abc"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := m.Set("abc", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(50 * time.Second)
+	if err := m.Touch("abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(50 * time.Second)
+	if data, err := m.Get("abc"); err != nil || string(data) != "state" {
+		t.Fatalf("expected the touched entry to live on, got %s %v", data, err)
+	}
+}
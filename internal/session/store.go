@@ -0,0 +1,60 @@
+// Package session provides server-side storage for the per-session algorithm
+// state, so that clients only ever hold an opaque session ID
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const idBytes = 16
+
+// ErrNotFound is returned when a session ID is unknown or has expired
+var ErrNotFound = errors.New("session not found")
+
+// Store - a backend capable of persisting opaque session data by ID
+type Store interface {
+	Get(id string) ([]byte, error)
+	Set(id string, data []byte) error
+	Delete(id string) error
+	// Touch restarts the TTL of the session without rewriting its data,
+	// ErrNotFound is returned if there is no live session
+	Touch(id string) error
+	// Lock holds the session until unlock is called, so that a request's load,
+	// modify then save of it is not interleaved with another's. It only locks
+	// out requests served by this process.
+	Lock(id string) (unlock func())
+}
+
+// New returns the Store for the backend name, either "memory" or "file"
+// dir is only used by the file backend
+func New(backend, dir string, ttl time.Duration) (Store, error) {
+	switch backend {
+	case "memory":
+		return NewMemoryStore(ttl), nil
+	case "file":
+		return NewFileStore(dir, ttl)
+	default:
+		return nil, fmt.Errorf("unknown session store: %s", backend)
+	}
+}
+
+// NewID generates a random, hex encoded session ID
+func NewID() (string, error) {
+	b := make([]byte, idBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func validID(id string) bool {
+	if len(id) != 2*idBytes {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
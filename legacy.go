@@ -0,0 +1,75 @@
+package algoexplore
+
+// LegacyAlgoPlugin - the AlgoPlugin contract before its methods returned
+// errors, when a plugin could only fail by panicking. A legacy plugin has no
+// notion of being done, it is stepped for as long as it is sent input, and
+// its output is only in its state.
+type LegacyAlgoPlugin interface {
+	Name() string
+	Init(inputLen int)
+	Step(d byte)
+	SerializeState() string
+	DeserializeState(state string) error
+}
+
+type LegacyAlgoFactory func() LegacyAlgoPlugin
+
+// FromLegacy adapts a LegacyAlgoPlugin to the AlgoPlugin contract, a panic
+// in any of its methods is returned as a *PanicError
+func FromLegacy(algo LegacyAlgoPlugin) AlgoPlugin {
+	return &legacyAdapter{algo}
+}
+
+// RegisterLegacy registers a LegacyAlgoPlugin, see Register
+func RegisterLegacy(algoFactory LegacyAlgoFactory) {
+	Register(func() AlgoPlugin { return FromLegacy(algoFactory()) })
+}
+
+type legacyAdapter struct {
+	plugin LegacyAlgoPlugin
+}
+
+func (l *legacyAdapter) Name() string {
+	return l.plugin.Name()
+}
+
+func (l *legacyAdapter) Init(inputLen int) error {
+	return protect(l.Name(), func() error {
+		l.plugin.Init(inputLen)
+		return nil
+	})
+}
+
+func (l *legacyAdapter) Step(d byte) error {
+	return protect(l.Name(), func() error {
+		l.plugin.Step(d)
+		return nil
+	})
+}
+
+func (l *legacyAdapter) SerializeState() (state string, err error) {
+	err = protect(l.Name(), func() error {
+		state = l.plugin.SerializeState()
+		return nil
+	})
+	return state, err
+}
+
+func (l *legacyAdapter) DeserializeState(state string) error {
+	return protect(l.Name(), func() error { return l.plugin.DeserializeState(state) })
+}
+
+// Done - a legacy plugin is never done
+func (l *legacyAdapter) Done() bool {
+	return false
+}
+
+// Output - a legacy plugin has no output outside of its state
+func (l *legacyAdapter) Output() (string, error) {
+	return "", nil
+}
+
+// Unwrap returns the adapted plugin
+func (l *legacyAdapter) Unwrap() interface{} {
+	return l.plugin
+}
@@ -0,0 +1,59 @@
+package algoexplore
+
+import (
+	"errors"
+	"testing"
+)
+
+// LegacyFake - a plugin written to the LegacyAlgoPlugin contract, which
+// panics on an input it cannot handle
+type LegacyFake struct {
+	sum int
+}
+
+func (l *LegacyFake) Name() string { return "legacyfake" }
+func (l *LegacyFake) Init(inputLen int) {
+	if inputLen < 1 {
+		panic("invalid input length")
+	}
+	l.sum = 0
+}
+func (l *LegacyFake) Step(d byte)                         { l.sum += int(d) }
+func (l *LegacyFake) SerializeState() string              { return "legacy" }
+func (l *LegacyFake) DeserializeState(state string) error { return nil }
+func (l *LegacyFake) Describe() AlgoInfo                  { return AlgoInfo{DisplayName: "Legacy Fake"} }
+
+func TestFromLegacy_withPanickingInit_ReturnsPanicError(t *testing.T) {
+	algo := FromLegacy(&LegacyFake{})
+
+	var p *PanicError
+	if err := algo.Init(0); !errors.As(err, &p) || p.Value != "invalid input length" {
+		t.Fatalf("expected the init panic as an error, got %v", err)
+	}
+
+	if err := algo.Init(1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := algo.Step(3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state, err := algo.SerializeState(); err != nil || state != "legacy" {
+		t.Fatalf("expected the legacy state, got %s: %v", state, err)
+	}
+	if output, err := algo.Output(); algo.Done() || output != "" || err != nil {
+		t.Fatalf("expected a legacy plugin to never be done, got %s: %v", output, err)
+	}
+}
+
+func TestRegisterLegacy_withDescriber_KeepsItsMetadata(t *testing.T) {
+	RegisterLegacy(func() LegacyAlgoPlugin { return &LegacyFake{} })
+
+	algo, err := GetAlgo("legacyfake")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info := Describe(algo); info.Name != "legacyfake" || info.DisplayName != "Legacy Fake" {
+		t.Fatalf("expected the legacy plugin's metadata, got %#v", info)
+	}
+}
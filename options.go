@@ -0,0 +1,155 @@
+package algoexplore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// OptionType - the JSON type of an option's value
+type OptionType string
+
+const (
+	// OptionInteger - a whole number, between Min and Max
+	OptionInteger OptionType = "integer"
+	// OptionString - a string, with a length between Min and Max
+	OptionString OptionType = "string"
+	// OptionBoolean - true or false
+	OptionBoolean OptionType = "boolean"
+)
+
+// OptionSchema - describes one option an algorithm takes at init
+// Name is the option's key in the JSON options object.
+// A Max of 0 means the value (or the length of a string) is unbounded.
+type OptionSchema struct {
+	Name    string      `json:"name"`
+	Label   string      `json:"label"`
+	Type    OptionType  `json:"type"`
+	Default interface{} `json:"default"`
+	Min     int64       `json:"min"`
+	Max     int64       `json:"max,omitempty"`
+	Help    string      `json:"help,omitempty"`
+	Order   int         `json:"order"`
+}
+
+// OptionsSchema - describes the options object of an algorithm, so that
+// requests can be validated and a form drawn for it without algorithm
+// specific code
+type OptionsSchema struct {
+	Options []OptionSchema `json:"options"`
+}
+
+// OptionsInitializer is optionally implemented by an AlgoPlugin that can be
+// configured at init, e.g. with a key, a polynomial or a seed.
+// opts is a JSON object described by OptionsSchema, any option left out takes
+// its default. opts is nil when no options were sent.
+type OptionsInitializer interface {
+	OptionsSchema() OptionsSchema
+	InitWithOptions(inputLen int, opts json.RawMessage) error
+}
+
+// OptionsOf returns the OptionsSchema of a plugin with its options in display
+// order, ok is false if the plugin takes no options
+func OptionsOf(algo AlgoPlugin) (schema OptionsSchema, ok bool) {
+	o, ok := unwrap(algo).(OptionsInitializer)
+	if !ok {
+		return OptionsSchema{Options: []OptionSchema{}}, false
+	}
+
+	schema = o.OptionsSchema()
+	options := make([]OptionSchema, len(schema.Options))
+	copy(options, schema.Options)
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Order < options[j].Order })
+
+	return OptionsSchema{Options: options}, true
+}
+
+// ValidateOptions checks that opts is a JSON object of only the options in
+// the schema, each of its type and within its bounds
+func ValidateOptions(schema OptionsSchema, opts json.RawMessage) error {
+	if len(opts) < 1 {
+		return nil
+	}
+
+	var r io.Reader
+	r = bytes.NewReader(opts)
+	var values map[string]json.RawMessage
+	if err := StrictUnmarshalJSON(&r, &values); err != nil {
+		return fmt.Errorf("options must be a JSON object: %v", err)
+	}
+
+	byName := make(map[string]OptionSchema, len(schema.Options))
+	for _, o := range schema.Options {
+		byName[o.Name] = o
+	}
+
+	for name, raw := range values {
+		o, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown option %q", name)
+		}
+		if err := o.validate(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InitAlgo initializes the plugin, with opts if it is an OptionsInitializer.
+// opts are validated against the plugin's OptionsSchema first, and a plugin
+// that takes no options can only be sent none.
+func InitAlgo(algo AlgoPlugin, inputLen int, opts json.RawMessage) error {
+	o, ok := unwrap(algo).(OptionsInitializer)
+	if !ok {
+		if len(opts) > 0 {
+			return fmt.Errorf("%s does not take options", algo.Name())
+		}
+		return algo.Init(inputLen)
+	}
+
+	if err := ValidateOptions(o.OptionsSchema(), opts); err != nil {
+		return err
+	}
+	return protect(algo.Name(), func() error { return o.InitWithOptions(inputLen, opts) })
+}
+
+func (o OptionSchema) validate(raw json.RawMessage) error {
+	var r io.Reader
+	r = bytes.NewReader(raw)
+
+	switch o.Type {
+	case OptionInteger:
+		var v int64
+		if err := StrictUnmarshalJSON(&r, &v); err != nil {
+			return fmt.Errorf("option %q must be an integer", o.Name)
+		}
+		if v < o.Min || (o.Max != 0 && v > o.Max) {
+			return o.outOfBounds("")
+		}
+	case OptionString:
+		var v string
+		if err := StrictUnmarshalJSON(&r, &v); err != nil {
+			return fmt.Errorf("option %q must be a string", o.Name)
+		}
+		if n := int64(len(v)); n < o.Min || (o.Max != 0 && n > o.Max) {
+			return o.outOfBounds("the length of ")
+		}
+	case OptionBoolean:
+		var v bool
+		if err := StrictUnmarshalJSON(&r, &v); err != nil {
+			return fmt.Errorf("option %q must be true or false", o.Name)
+		}
+	default:
+		return fmt.Errorf("option %q has an unknown type %q", o.Name, o.Type)
+	}
+	return nil
+}
+
+func (o OptionSchema) outOfBounds(what string) error {
+	if o.Max == 0 {
+		return fmt.Errorf("%soption %q must be at least %d", what, o.Name, o.Min)
+	}
+	return fmt.Errorf("%soption %q must be between %d and %d", what, o.Name, o.Min, o.Max)
+}
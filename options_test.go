@@ -0,0 +1,103 @@
+package algoexplore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type OptionsFake struct {
+	Fake
+	seed  int64
+	inits int
+}
+
+func (fake *OptionsFake) OptionsSchema() OptionsSchema {
+	return OptionsSchema{Options: []OptionSchema{
+		{Name: "name", Type: OptionString, Default: "x", Min: 1, Max: 3, Order: 2},
+		{Name: "seed", Type: OptionInteger, Default: 1, Min: 1, Max: 10, Order: 1},
+		{Name: "verbose", Type: OptionBoolean, Default: false, Order: 3},
+	}}
+}
+
+func (fake *OptionsFake) InitWithOptions(inputLen int, opts json.RawMessage) error {
+	fake.inits++
+	var o struct {
+		Seed    int64  `json:"seed"`
+		Name    string `json:"name"`
+		Verbose bool   `json:"verbose"`
+	}
+	if len(opts) > 0 {
+		if err := json.Unmarshal(opts, &o); err != nil {
+			return err
+		}
+	}
+	fake.seed = o.Seed
+	return nil
+}
+
+func TestOptionsOf_withOptionsInitializer_SortsOptionsByOrder(t *testing.T) {
+	schema, ok := OptionsOf(&OptionsFake{})
+	if !ok {
+		t.Fatal("expected options")
+	}
+
+	var names string
+	for _, o := range schema.Options {
+		names += o.Name + ","
+	}
+
+	if names != "seed,name,verbose," {
+		t.Fatalf("expected options in display order, got %s", names)
+	}
+}
+
+func TestOptionsOf_withoutOptionsInitializer_IsNotOk(t *testing.T) {
+	schema, ok := OptionsOf(&Fake{})
+	if ok || schema.Options == nil || len(schema.Options) != 0 {
+		t.Fatalf("expected no options, got %#v", schema)
+	}
+}
+
+func TestValidateOptions_withInvalidOptions_ReturnsError(t *testing.T) {
+	schema := (&OptionsFake{}).OptionsSchema()
+
+	for _, opts := range []string{
+		`[1]`,
+		`{"seed": 0}`,
+		`{"seed": 11}`,
+		`{"seed": 1.5}`,
+		`{"seed": "1"}`,
+		`{"name": ""}`,
+		`{"name": "abcd"}`,
+		`{"verbose": 1}`,
+		`{"unknown": 1}`,
+	} {
+		if err := ValidateOptions(schema, json.RawMessage(opts)); err == nil {
+			t.Errorf("%s: expected an error", opts)
+		}
+	}
+
+	for _, opts := range []string{``, `{}`, `{"seed": 10, "name": "abc", "verbose": true}`} {
+		if err := ValidateOptions(schema, json.RawMessage(opts)); err != nil {
+			t.Errorf("%s: unexpected error: %v", opts, err)
+		}
+	}
+}
+
+func TestInitAlgo_withOptions_InitsOnlyValidOptions(t *testing.T) {
+	fake := &OptionsFake{}
+	if err := InitAlgo(fake, 1, json.RawMessage(`{"seed": 7}`)); err != nil || fake.seed != 7 {
+		t.Fatalf("expected seed 7, got %d: %v", fake.seed, err)
+	}
+
+	if err := InitAlgo(fake, 1, json.RawMessage(`{"seed": 70}`)); err == nil || fake.inits != 1 {
+		t.Fatalf("expected invalid options to be rejected before init, got %v", err)
+	}
+
+	if err := InitAlgo(&Fake{}, 1, json.RawMessage(`{"seed": 7}`)); err == nil {
+		t.Fatal("expected options to be rejected by a plugin that takes none")
+	}
+	if err := InitAlgo(&Fake{}, 1, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package algoexplore
+
+import "sort"
+
+// FieldKind - how a field of an algorithm's state is to be visualized
+type FieldKind string
+
+const (
+	// KindBitArray - an array of 0/1 values, drawn as one cube per bit
+	KindBitArray FieldKind = "bit_array"
+	// KindByteArray - an array of integers, drawn as one cube per element in hex
+	KindByteArray FieldKind = "byte_array"
+	// KindScalar - a single number or string, drawn in a box
+	KindScalar FieldKind = "scalar"
+	// KindRegister - a fixed width integer, drawn as its Bits least significant bits
+	KindRegister FieldKind = "register"
+	// KindOutput - a part of the algorithm's output, parts are joined with ':'
+	KindOutput FieldKind = "output"
+	// KindTrigger - a flag that highlights the current input byte whenever it is set
+	KindTrigger FieldKind = "trigger"
+	// KindTable - an array of objects, drawn as one row of Columns per element,
+	// the row indexed by the field at the Highlight path is highlighted
+	KindTable FieldKind = "table"
+)
+
+// FieldSchema - describes one field of an algorithm's serialized state
+// Path is the dotted JSON path to the field, e.g. "rolling_hash.window"
+type FieldSchema struct {
+	Path  string    `json:"path"`
+	Label string    `json:"label"`
+	Kind  FieldKind `json:"kind"`
+	Bits  int       `json:"bits,omitempty"`
+	Order int       `json:"order"`
+
+	Columns   []string `json:"columns,omitempty"`
+	Highlight string   `json:"highlight,omitempty"`
+}
+
+// StateSchema - describes the serialized state of an algorithm so that it
+// can be drawn without an algorithm specific renderer
+type StateSchema struct {
+	Fields []FieldSchema `json:"fields"`
+}
+
+// StateDescriber is optionally implemented by an AlgoPlugin whose
+// SerializeState returns JSON, to describe that JSON
+type StateDescriber interface {
+	StateSchema() StateSchema
+}
+
+// SchemaOf returns the StateSchema of a plugin with its fields in display
+// order, ok is false if the plugin does not describe its state
+func SchemaOf(algo AlgoPlugin) (schema StateSchema, ok bool) {
+	d, ok := unwrap(algo).(StateDescriber)
+	if !ok {
+		return StateSchema{}, false
+	}
+
+	schema = d.StateSchema()
+	fields := make([]FieldSchema, len(schema.Fields))
+	copy(fields, schema.Fields)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Order < fields[j].Order })
+
+	return StateSchema{Fields: fields}, true
+}
@@ -0,0 +1,37 @@
+package algoexplore
+
+import (
+	"testing"
+)
+
+type SchemaFake struct{ Fake }
+
+func (fake *SchemaFake) StateSchema() StateSchema {
+	return StateSchema{Fields: []FieldSchema{
+		{Path: "c", Kind: KindScalar, Order: 3},
+		{Path: "a", Kind: KindRegister, Bits: 32, Order: 1},
+		{Path: "b", Kind: KindTrigger, Order: 2},
+	}}
+}
+
+func TestSchemaOf_withStateDescriber_SortsFieldsByOrder(t *testing.T) {
+	schema, ok := SchemaOf(&SchemaFake{})
+	if !ok {
+		t.Fatal("expected a schema")
+	}
+
+	var paths string
+	for _, f := range schema.Fields {
+		paths += f.Path
+	}
+
+	if paths != "abc" {
+		t.Fatalf("expected fields in display order 'abc', got %s", paths)
+	}
+}
+
+func TestSchemaOf_withoutStateDescriber_IsNotOk(t *testing.T) {
+	if _, ok := SchemaOf(&Fake{}); ok {
+		t.Fatal("expected no schema for a plugin that does not describe its state")
+	}
+}
@@ -0,0 +1,75 @@
+// Package ssdeep computes and compares ssdeep fuzzy hashes using the same
+// CTPH step engine that the algoexplore web server visualizes
+package ssdeep
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/joekir/algoexplore/internal/algos/ctph"
+)
+
+// HashBytes returns the ssdeep signature of data
+func HashBytes(data []byte) (string, error) {
+	return ctph.HashSteps(bytes.NewReader(data), int64(len(data)))
+}
+
+// HashReader returns the ssdeep signature of everything read from r. A retry
+// at a smaller block size steps through the input again, so all of it is read
+// into memory first, HashFile rereads the file instead.
+func HashReader(r io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	return HashBytes(data)
+}
+
+// HashFile returns the ssdeep signature of the file at path
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		// a pipe or device cannot be rewound, nor its size known
+		return HashReader(f)
+	}
+
+	return ctph.HashSteps(f, info.Size())
+}
+
+// Compare returns the similarity of two ssdeep signatures, from 0 (no match)
+// to 100 (identical). Signatures whose block sizes are too far apart to be
+// compared score 0, malformed signatures return an error
+func Compare(a, b string) (int, error) {
+	return ctph.Score(a, b)
+}
+
+// Index - a set of signatures to find those similar to another without
+// comparing every one, see NewIndex
+type Index = ctph.Index
+
+// IndexMatch - a signature of an Index that matched a query, and its score
+type IndexMatch = ctph.IndexMatch
+
+// NewIndex returns an empty Index. Signatures are added to it by ID, and a
+// query only compares the signatures that share a 7 character run with it at
+// a comparable block size, as ssdeep backed databases do
+func NewIndex() *Index {
+	return ctph.NewIndex()
+}
+
+// LoadIndexFile reads an Index saved with its SaveFile method
+func LoadIndexFile(path string) (*Index, error) {
+	return ctph.LoadIndexFile(path)
+}
@@ -0,0 +1,161 @@
+package ssdeep
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/joekir/algoexplore/internal/algos/ctph"
+)
+
+const (
+	crowAndFox     = "../internal/algos/ctph/testdata/crowandthefox.txt"
+	crowAndFoxHash = "24:O7XC9FZ2LBfaW3h+XdcDljuQJtNMMqF5DjQuwM0OHC:O7S9FZ2LwWEdcM6tNMjDEuwwHC"
+)
+
+func TestHashFile_WithCrowAndFox_MatchesExistingTool(t *testing.T) {
+	h, err := HashFile(crowAndFox)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if h != crowAndFoxHash {
+		t.Fatalf("unexpected hash: %s", h)
+	}
+}
+
+func TestHashFile_WithMissingFile_ReturnsError(t *testing.T) {
+	if _, err := HashFile("testdata/missing.txt"); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+}
+
+func TestHashReader_MatchesHashBytes(t *testing.T) {
+	data, err := ioutil.ReadFile(crowAndFox)
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+
+	h1, err := HashBytes(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h2, err := HashReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if h1 != h2 {
+		t.Fatalf("expected %s, got %s", h1, h2)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestHashReader_WithFailingReader_ReturnsError(t *testing.T) {
+	if _, err := HashReader(errReader{}); err == nil || err.Error() != "read failed" {
+		t.Fatalf("expected read error, got %v", err)
+	}
+}
+
+func TestCompare_WithCrowAndFoxSlightlyTweaked_IsSimilar(t *testing.T) {
+	data, err := ioutil.ReadFile(crowAndFox)
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+
+	h, err := HashBytes([]byte(strings.Replace(string(data), "vous", "tous", 1)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	score, err := Compare(crowAndFoxHash, h)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if score < 90 || score > 99 {
+		t.Fatalf("comparison score (%d) incorrect for hashes:\n%s\n%s\n", score, crowAndFoxHash, h)
+	}
+
+	if score, _ := Compare(crowAndFoxHash, crowAndFoxHash); score != 100 {
+		t.Fatalf("expected identical hashes to score 100, got %d", score)
+	}
+}
+
+func TestCompare_WithInvalidSignature_ReturnsError(t *testing.T) {
+	if _, err := Compare("24O7XC9FZ2LBfaW3hM6tNMjDEuwwHC", crowAndFoxHash); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+// repeatReader yields n bytes of a repeating pattern without holding them all
+type repeatReader struct {
+	pattern []byte
+	n, pos  int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.pos >= r.n {
+		return 0, io.EOF
+	}
+
+	i := 0
+	for ; i < len(p) && r.pos < r.n; i++ {
+		p[i] = r.pattern[r.pos%len(r.pattern)]
+		r.pos++
+	}
+	return i, nil
+}
+
+func TestHashReader_WithLargeStream_MatchesTheStream(t *testing.T) {
+	pattern := []byte("The quick brown fox jumped over the lazy dog's back, 0123456789\n")
+	n := 4<<20 + 17
+
+	h, err := HashReader(&repeatReader{pattern: pattern, n: n})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := ioutil.ReadAll(&repeatReader{pattern: pattern, n: n})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ctph.Hash(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h != expected {
+		t.Fatalf("expected %s, got %s", expected, h)
+	}
+}
+
+func TestIndex_withCrowAndFox_FindsTheTweakedCopy(t *testing.T) {
+	ix := NewIndex()
+	if err := ix.Add("crow", crowAndFoxHash); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	original, err := ioutil.ReadFile(crowAndFox)
+	if err != nil {
+		t.Fatalf("could not read test file: %v", err)
+	}
+	tweaked, err := HashBytes(bytes.Replace(original, []byte("vous"), []byte("tous"), 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	matches, err := ix.Query(tweaked, 50)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != "crow" {
+		t.Fatalf("expected the original to match, got %v", matches)
+	}
+}
@@ -7,29 +7,49 @@ if (typeof counterColour === 'undefined'){
   const elemFontSize = "0.22em",
        titleFontSize = "0.22em";
 
+  // inputs longer than this are drawn as a window of bytes around the cursor
+  const maxCubes = 64;
+
   var cubeWidth = 10,
         xBuffer = 290,
         yBuffer = 0,
          svgDoc = d3.selectAll("svg");
 
   var updateSizing = () => {
-    var inputText = $("#algo-input")[0].value;
-    var inputBytes = strToByteArr(inputText);
+    var inputBytes = currentInputBytes();
 
-    if (typeof inputBytes !== 'undefined') {
-      cubeWidth = xBuffer / inputBytes.length;
+    if (inputBytes.length > 0) {
+      cubeWidth = xBuffer / Math.min(inputBytes.length, maxCubes);
     }
   };
 
-  var strToByteArr = (str) => {
-    var arr = [];
-    for (var i = 0; i < str.length; i++) {
-      arr.push(str.charCodeAt(i));
+  // The bytes being stepped, as encoded by the server at init
+  var currentInputBytes = () => {
+    if (typeof stepBytes === 'undefined') {
+      return [];
     }
+    return stepBytes;
+  };
+
+  // The input is shown as its printable ascii characters
+  var printable = (bytes) => {
+    return bytes.map((b) => (b >= 0x20 && b < 0x7f ? String.fromCharCode(b) : "."));
+  };
 
-    return arr;
+  // The first position of the window of input drawn around the cursor
+  var windowStart = (length, pos) => {
+    var start = Math.min(pos - maxCubes / 2, length - maxCubes);
+    return Math.max(0, start);
+  };
+
+  // The server reports the input as base64 of the exact bytes it steps
+  var base64ToByteArr = (str) => {
+    return Array.from(atob(str || ""), (c) => c.charCodeAt(0));
   }
 
+  // whether the algorithm is done and its output, from the last response
+  var progress = { done: false, output: "" };
+
   var bitArray = (arr) => {
     var output = []; // there is no bit array :(
     for (var i = 0; i < arr.length; i++) {
@@ -46,14 +66,24 @@ if (typeof counterColour === 'undefined'){
     return output;
   }
 
-  var appendArray = (title, backingArray, highlight) => {
+  // Resolves a dotted schema path, e.g. "rolling_hash.window", within the state
+  var lookup = (state, path) => {
+    return path.split(".").reduce((obj, key) => (obj == null ? obj : obj[key]), state);
+  }
+
+  var fieldsOfKind = (kind) => {
+    return schema.fields.filter((field) => field.kind === kind);
+  }
+
+  var appendArray = (title, backingArray, highlight, width, onClick) => {
     var items = svgDoc.selectAll("g");
+    var w = width || cubeWidth;
 
     items.data([title])
       .enter()
       .append("text")
       .style("font-size", titleFontSize)
-      .attr("x", xBuffer + cubeWidth)
+      .attr("x", xBuffer + w)
       .style("text-anchor", "end")
       .attr("y", yBuffer)
       .text(d => d);
@@ -61,20 +91,23 @@ if (typeof counterColour === 'undefined'){
     items.data(backingArray)
       .enter()
       .append("rect")
-      .attr("x", (d, i) => { return (xBuffer - i * cubeWidth); })
-      .attr("y", yBuffer + 0.4 * cubeWidth)
-      .attr("width", cubeWidth)
-      .attr("height", cubeWidth)
-      .style("fill", highlight);
+      .attr("x", (d, i) => { return (xBuffer - i * w); })
+      .attr("y", yBuffer + 0.4 * w)
+      .attr("width", w)
+      .attr("height", w)
+      .attr("data-index", (d, i) => i)
+      .style("cursor", onClick ? "pointer" : null)
+      .style("fill", highlight)
+      .on("click", onClick ? (e) => onClick(Number(e.target.dataset.index)) : null);
 
     items.data(backingArray)
       .enter()
       .append("text")
       .text((d) => d.toString(16)) // mostly this will be bits, but if not hex it
       .style("font-size", elemFontSize)
-      .attr("x", (d, i) => { return (xBuffer - (i - 0.5) * cubeWidth)})
+      .attr("x", (d, i) => { return (xBuffer - (i - 0.5) * w)})
       .attr("text-anchor", "middle")
-      .attr("y", yBuffer + cubeWidth)
+      .attr("y", yBuffer + w)
       .attr("dominant-baseline", "middle");
   }
 
@@ -135,21 +168,59 @@ if (typeof counterColour === 'undefined'){
       .attr("y", yBuffer + 0.60 * cubeWidth);
   }
 
+  // Draws one row of the columns per element of rows, the row at highlightIndex
+  // (if any) is filled with the counter colour
+  var appendTable = (title, rows, columns, highlightIndex) => {
+    var items = svgDoc.selectAll("g");
+    var rowHeight = 1.2 * cubeWidth;
+    var width = xBuffer;
+
+    items.data([title + " (" + columns.join(", ") + ")"])
+      .enter()
+      .append("text")
+      .style("font-size", titleFontSize)
+      .attr("x", xBuffer + cubeWidth)
+      .style("text-anchor", "end")
+      .attr("y", yBuffer)
+      .text(d => d);
+
+    items.data(rows)
+      .enter()
+      .append("rect")
+      .attr("x", cubeWidth)
+      .attr("y", (d, i) => { return yBuffer + 0.4 * cubeWidth + i * rowHeight; })
+      .attr("width", width)
+      .attr("height", rowHeight)
+      .style("fill", (d, i) => { return i === highlightIndex ? counterColour : "none"; });
+
+    items.data(rows)
+      .enter()
+      .append("text")
+      .style("font-size", elemFontSize)
+      .text((d) => columns.map((c) => d[c]).join("  "))
+      .attr("x", 1.25 * cubeWidth)
+      .attr("y", (d, i) => { return yBuffer + cubeWidth + i * rowHeight; })
+      .attr("dominant-baseline", "middle");
+
+    return rows.length * rowHeight;
+  }
+
   var noop = (d, i) => null;
 
-  var input = (hits, doubleHits, pos) => function (d, i) {
+  // hits holds, per trigger field, the input positions at which it fired
+  // offset is the position of the first element drawn
+  var input = (hits, pos, offset) => function (d, j) {
     var ctr = 0;
+    var i = j + offset;
 
-    if (hits.includes(i)) {
-      ctr += 1;
-    }
-
-    if (doubleHits.includes(i)) {
-      ctr += 2;
-    }
+    hits.forEach((positions, t) => {
+      if (positions.includes(i)) {
+        ctr += 1 << t;
+      }
+    });
 
     if (ctr > 0) {
-      return hitColours[ctr - 1];
+      return hitColours[Math.min(ctr, hitColours.length) - 1];
     }
 
     if (i == pos) {
@@ -157,50 +228,215 @@ if (typeof counterColour === 'undefined'){
     }
   };
 
-  var render = (inputText, inputBytes) => {
-    var inputText = $("#algo-input")[0].value;
-    var inputBytes = strToByteArr(inputText);
+  // Draws every field of the state in schema order, by the kind of the field
+  var renderState = (state) => {
+    var scalarTitles = [],
+        scalarValues = [],
+        outputs = [];
+
+    schema.fields.forEach((field) => {
+      var value = lookup(state, field.path);
+      if (value == null) {
+        return;
+      }
+
+      switch (field.kind) {
+        case "bit_array":
+          appendArray(field.label, value.map((b) => (b ? 1 : 0)), noop);
+          yBuffer += 3 * cubeWidth;
+          break;
+        case "byte_array":
+          appendArray(field.label, value, noop);
+          yBuffer += 3 * cubeWidth;
+          break;
+        case "register":
+          var bits = bitArray([value]).slice(0, field.bits || 32);
+          var width = Math.min(cubeWidth, xBuffer / bits.length);
+          appendArray(field.label + " (bits)", bits, noop, width);
+          yBuffer += 3 * width;
+          break;
+        case "table":
+          var height = appendTable(field.label, value, field.columns || [],
+                                   field.highlight ? lookup(state, field.highlight) : -1);
+          yBuffer += height + 2 * cubeWidth;
+          break;
+        case "scalar":
+          scalarTitles.push(field.label);
+          scalarValues.push(value.toString(10));
+          break;
+        case "output":
+          outputs.push(value);
+          break;
+        // triggers are drawn as highlights of the input
+      }
+    });
+
+    if (scalarTitles.length > 0) {
+      appendText(scalarTitles.reverse(), scalarValues.reverse());
+    }
+
+    // the algorithm's own output, over the output fields, once it has any
+    var output = progress.output || outputs.join(":");
+    $("#algo-output").get(0).value = output;
+    $("#algo-output").toggleClass("is-success", progress.done);
+  };
+
+  // Keeps whether the algorithm is done and its output from a state response
+  var recordProgress = (data) => {
+    progress = { done: data.done, output: data.output }; // GLOBAL
+  };
+
+  var render = () => {
+    var inputBytes = currentInputBytes();
+    var start = windowStart(inputBytes.length, ctr);
+    var shown = inputBytes.slice(start, start + maxCubes);
+    var shownText = printable(shown);
+    var moveToShown = (j) => moveTo(j + start);
 
     var dBits = bitArray([inputBytes[ctr]]);
-    yBuffer = 0; 
+    var triggers = fieldsOfKind("trigger").map((field) => field.label);
+    yBuffer = 0;
 
     svgDoc.html(null);
 
-    appendLegend(["ModBS", "Mod2BS", "Both"], hitColours);
+    if (triggers.length == 2) {
+      triggers.push("Both");
+    }
+    appendLegend(triggers, hitColours.slice(0, triggers.length));
     yBuffer += 2 * cubeWidth;
-    appendArray("Input Text", inputText, input(hits, doubleHits, ctr));
+    if (shown.length < inputBytes.length) {
+      appendText([`Input ${start}-${start + shown.length - 1} of ${inputBytes.length} bytes`], []);
+      yBuffer += 2 * cubeWidth;
+    }
+    appendArray("Input Text", shownText, input(hits, ctr, start), cubeWidth, moveToShown);
     yBuffer += 3 * cubeWidth;
-    appendArray("Input Bytes (hex)", inputBytes, input(hits, doubleHits, ctr));
+    appendArray("Input Bytes (hex)", shown, input(hits, ctr, start), cubeWidth, moveToShown);
     yBuffer += 3 * cubeWidth;
     appendArray("Bits of current selection (d)", dBits.slice(0, 8), noop);
     yBuffer += 3 * cubeWidth;
-    appendArray("Window Array (hex)", fh.rolling_hash.window, noop);
-    yBuffer += 3 * cubeWidth;
 
-    appendText(["Z Value", "Y Value", "X Value"],
-      [fh.rolling_hash.z.toString(10),
-      fh.rolling_hash.y.toString(10),
-      fh.rolling_hash.x.toString(10)]);
+    renderState(fh);
+  };
+
+  // Loads the description of the algorithm's state, without one only the
+  // input is drawn
+  function fetchSchema(algoPath) {
+    schema = { fields: [] }; // GLOBAL
 
-    var sig = fh.block_size + ":" + fh.sig1 + ":" + fh.sig2;
-    $("#algo-output").get(0).value = sig;
+    $.ajax({
+      async: false,
+      dataType: "json",
+      type: "GET",
+      url: `${algoPath}/schema`,
+    })
+      .fail(function (a, b, c) {
+        console.log(a, b, c);
+        console.log("no schema");
+      })
+      .done(function (data) {
+        schema = data;
+      });
+  }
+
+  // Records which triggers fired in the state for the input at position pos
+  var recordHits = (state, pos) => {
+    fieldsOfKind("trigger").forEach((field, t) => {
+      if (lookup(state, field.path)) {
+        hits[t].push(pos);
+      }
+    });
   };
 
-  function stepAlgo() {
+  // Forgets the hits of any input after position pos
+  var pruneHits = (pos) => {
+    hits = hits.map((positions) => positions.filter((i) => i <= pos)); // GLOBAL
+  };
+
+  // Moves the algorithm to just after the input at position pos of the current
+  // pass, rewinding through the server-side history or stepping forwards in a
+  // single batch
+  var moveTo = (pos) => {
+    if (pos <= ctr) {
+      seekAlgo(steps - ctr + pos);
+      return;
+    }
+
+    var algoPath = localStorage.getItem("algoPathName");
+
+    $.ajax({
+      async: false,
+      contentType: "application/json; charset=utf-8",
+      data: JSON.stringify({ count: pos - ctr, states: true }),
+      dataType: "json",
+      type: "POST",
+      url: `${algoPath}/steps`,
+    })
+      .fail(function (a, b, c) {
+        console.log(a, b, c);
+        console.log("failed");
+      })
+      .done(function (data) {
+        data.states.forEach((state) => {
+          ctr++;
+          recordHits(JSON.parse(state), ctr);
+        });
+        fh = JSON.parse(data.state); // GLOBAL
+        ctr = data.cursor - 1;
+        steps += data.count;
+        recordProgress(data);
+        render();
+      });
+  };
+
+  // Moves the algorithm to the state after index steps since init
+  function seekAlgo(index) {
     var algoPath = localStorage.getItem("algoPathName");
     if (algoPath == null) {
       return;
     }
 
-    var inputText = $("#algo-input")[0].value;
-    var inputBytes = strToByteArr(inputText);
+    $.ajax({
+      async: false,
+      contentType: "application/json; charset=utf-8",
+      data: JSON.stringify({ index: index }),
+      dataType: "json",
+      type: "POST",
+      url: `${algoPath}/seek`,
+    })
+      .fail(function (a, b, c) {
+        console.log(a, b, c);
+        console.log("failed");
+      })
+      .done(function (data) {
+        // GLOBALS
+        fh = JSON.parse(data.state);
+        ctr = data.cursor - 1;
+        steps = index;
+        recordProgress(data);
+        pruneHits(ctr);
+        render();
+      });
+  }
+
+  function backAlgo() {
+    if (steps < 1) {
+      return;
+    }
 
-    ctr++;
+    seekAlgo(steps - 1);
+  }
+
+  function stepAlgo() {
+    var algoPath = localStorage.getItem("algoPathName");
+    // the server refuses steps once the algorithm is done
+    if (algoPath == null || progress.done) {
+      return;
+    }
 
     $.ajax({
       async: false,
       contentType: "application/json; charset=utf-8",
-      data: JSON.stringify({ byte: inputBytes[ctr] }),
+      data: JSON.stringify({}),
       dataType: "json",
       type: "POST",
       url: `${algoPath}/step`,
@@ -210,18 +446,25 @@ if (typeof counterColour === 'undefined'){
         console.log("failed");
       })
       .done(function (data) {
-        data = JSON.parse(data);
-
-        if (null != data) {
-          fh = data; // GLOBAL
-          if (fh.is_trigger1) {
-            hits.push(ctr);
-          }
-          if (fh.is_trigger2) {
-            doubleHits.push(ctr);
-          }
-          render();
+        // no content once the algorithm needs no more input
+        if (null == data) {
+          return;
+        }
+
+        // GLOBALS
+        var consumed = ctr + 1;
+        fh = JSON.parse(data.state);
+        ctr = data.cursor - 1;
+        steps++;
+        recordProgress(data);
+
+        if (ctr < consumed) {
+          // the step completed a pass and the input starts over
+          hits = hits.map(() => []);
+        } else {
+          recordHits(fh, consumed);
         }
+        render();
       });
   }
 
@@ -231,42 +474,130 @@ if (typeof counterColour === 'undefined'){
       return;
     }
 
-    // stick with var throughout for these two, over var
+    // stick with var throughout for these two, over let
     // in case of redefine being an issue
     var inputText = $("#algo-input")[0].value;
-    var inputBytes = strToByteArr(inputText);
-
-    $.ajax({
+    var request = {
       async: false,
       contentType: "application/json; charset=utf-8",
-      data: JSON.stringify({ data_length: inputBytes.length }),
+      data: JSON.stringify({ text: inputText, encoding: $("#algo-encoding").val(), options: algoOptions() }),
       dataType: "json",
       type: "POST",
       url: `${algoPath}/init`,
-    })
+    };
+
+    if (typeof uploadedFile !== 'undefined' && uploadedFile != null) {
+      var form = new FormData();
+      form.append("file", uploadedFile);
+      if (algoOptions() !== undefined) {
+        form.append("options", JSON.stringify(algoOptions()));
+      }
+
+      request.contentType = false;
+      request.processData = false;
+      request.data = form;
+      request.url = `${algoPath}/upload`;
+    }
+
+    fetchSchema(algoPath);
+
+    $.ajax(request)
       .fail(function (a, b, c) {
         console.log(a, b, c);
         console.log("failed");
       })
-      .done(function (response) {
-        var parsed = JSON.parse(response);
+      .done(startAt);
+  }
 
-        // GLOBALS
-        fh = parsed;
-        ctr = fh.index;
-        hits = [];
-        doubleHits = [];
-        updateSizing();
-        render();
-      });
+  // Starts drawing a new session from its init or loaded trace response,
+  // count is the number of steps a loaded trace had taken
+  var startAt = (response) => {
+    // GLOBALS
+    fh = JSON.parse(response.state);
+    stepBytes = base64ToByteArr(response.input);
+    ctr = response.cursor - 1;
+    steps = response.count || 0;
+    recordProgress(response);
+    hits = fieldsOfKind("trigger").map(() => []);
+    updateSizing();
+    render();
+  };
+
+  // Downloads the run so far with every state, which can be loaded again to
+  // share it or attach it to a bug
+  function saveTrace() {
+    var algoPath = localStorage.getItem("algoPathName");
+    if (algoPath == null) {
+      return;
+    }
+
+    window.location.href = `${algoPath}/trace?states=true`;
   }
 
+  // Replays a saved trace, switching to its algorithm, the server verifies
+  // every state it recorded
+  var loadTrace = (e) => {
+    var file = e.target.files[0];
+    if (!file) {
+      return;
+    }
+
+    file.text().then((text) => {
+      var algoPath = "/" + JSON.parse(text).algo;
+      if (!algoPaths().includes(algoPath)) {
+        console.log("unknown algo in trace: ", algoPath);
+        return;
+      }
+
+      localStorage.setItem("algoPathName", algoPath);
+      describeAlgo(algoPath, false);
+      fetchSchema(algoPath);
+
+      $.ajax({
+        async: false,
+        contentType: "application/json; charset=utf-8",
+        data: text,
+        dataType: "json",
+        type: "POST",
+        url: `${algoPath}/trace`,
+      })
+        .fail(function (a, b, c) {
+          console.log(a, b, c);
+          console.log("failed");
+        })
+        .done(startAt);
+    });
+    $("#trace-file").val("");
+  };
+
   window.onresize = () => {
     updateSizing();
     render();
   };
 
+  // Switches to stepping the raw bytes of a file, until the text is edited
+  var selectFile = (e) => {
+    var file = e.target.files[0];
+    if (!file) {
+      return;
+    }
+
+    uploadedFile = file; // GLOBAL
+    $("#algo-file-name").text(file.name);
+    initAlgo();
+  };
+
+  var selectText = () => {
+    uploadedFile = null; // GLOBAL
+    $("#algo-file").val("");
+    $("#algo-file-name").text("Or upload a file");
+  };
+
   $(document).ready(() => {
+    // this script is re-run whenever the app is reloaded, so rebind rather than stack handlers
+    $("#algo-file").off("change").on("change", selectFile);
+    $("#algo-input").off("input").on("input", selectText);
+    $("#trace-file").off("change").on("change", loadTrace);
     initAlgo();
   });
 }
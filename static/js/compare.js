@@ -0,0 +1,97 @@
+// Compares two inputs through POST /ctph/compare and shows which chunks of
+// each input produced the signature characters the two have in common
+
+// A distinct light colour for the n'th matching chunk
+const chunkColour = (n) => `hsl(${(n * 47) % 360}, 70%, 80%)`;
+
+// Inputs are shown as their printable ascii characters
+const printableText = (bytes) => {
+  return Array.from(bytes, (b) => (b >= 0x20 && b < 0x7f) || b == 0x0a ? String.fromCharCode(b) : ".").join("");
+};
+
+const base64ToBytes = (str) => Array.from(atob(str || ""), (c) => c.charCodeAt(0));
+
+// The pair of signature parts that decided the score
+function bestPair(pairs) {
+  return pairs.reduce((best, pair) => (best == null || pair.score > best.score ? pair : best), null);
+}
+
+// Lines up the two signature parts, one character per edit operation
+function renderAlignment(pair, partA, partB, colours) {
+  let rows = [$("<div>"), $("<div>"), $("<div>")];
+  pair.ops.forEach((op, i) => {
+    let colour = op.op === "match" ? colours[i] : "";
+    $("<span>").text(op.a >= 0 ? partA[op.a] : "-").css("background-color", colour).appendTo(rows[0]);
+    $("<span>").text({ match: "|", replace: "x" }[op.op] || " ").appendTo(rows[1]);
+    $("<span>").text(op.b >= 0 ? partB[op.b] : "-").css("background-color", colour).appendTo(rows[2]);
+  });
+
+  $("#compare-alignment").empty().append(rows);
+}
+
+// Draws the input with each signature character's chunk marked, chunks
+// behind matching characters share their colour with the other input
+function renderChunks(target, bytes, chunks, part, colourOf) {
+  let elem = $(target).empty();
+  let pos = 0;
+  chunks.forEach((chunk, k) => {
+    if (chunk.start > pos) {
+      $("<span>").text(printableText(bytes.slice(pos, chunk.start))).appendTo(elem);
+    }
+    $("<span>", { class: "compare-chunk" })
+      .text(printableText(bytes.slice(chunk.start, chunk.end)))
+      .attr("title", `'${part[k]}' from bytes ${chunk.start} to ${chunk.end}`)
+      .css("background-color", colourOf[k] || "")
+      .appendTo(elem);
+    pos = chunk.end;
+  });
+  if (pos < bytes.length) {
+    $("<span>").text(printableText(bytes.slice(pos))).appendTo(elem);
+  }
+}
+
+function renderComparison(resp) {
+  $("#compare-score").text(`score ${resp.score}`);
+  $("#compare-alignment, #compare-chunks-a, #compare-chunks-b").empty();
+
+  let pair = bestPair(resp.pairs);
+  if (pair == null) {
+    $("#compare-alignment").text(`${resp.signatures[0]}\n${resp.signatures[1]}\nblock sizes too far apart to compare`);
+    return;
+  }
+
+  let partA = resp.signatures[0].split(":")[pair.parts[0]];
+  let partB = resp.signatures[1].split(":")[pair.parts[1]];
+
+  let colours = {}, colourA = {}, colourB = {}, n = 0;
+  pair.ops.forEach((op, i) => {
+    if (op.op === "match") {
+      colours[i] = colourA[op.a] = colourB[op.b] = chunkColour(n++);
+    }
+  });
+
+  renderAlignment(pair, partA, partB, colours);
+  renderChunks("#compare-chunks-a", base64ToBytes(resp.inputs[0]),
+    resp.chunks[0]["sig" + pair.parts[0]], partA, colourA);
+  renderChunks("#compare-chunks-b", base64ToBytes(resp.inputs[1]),
+    resp.chunks[1]["sig" + pair.parts[1]], partB, colourB);
+}
+
+function compareInputs() {
+  let encoding = $("#algo-encoding").val();
+  let inputs = [$("#compare-a").val(), $("#compare-b").val()].map((text) => ({ text: text, encoding: encoding }));
+
+  $.ajax({
+    contentType: "application/json; charset=utf-8",
+    data: JSON.stringify({ inputs: inputs }),
+    dataType: "json",
+    type: "POST",
+    url: "ctph/compare",
+  })
+  .fail(function (error) {
+    console.log("ajax failed: ", error);
+    let body = error.responseJSON;
+    $("#compare-score").text(body && body.error ? body.error.message : "failed");
+  })
+  .done(renderComparison);
+}
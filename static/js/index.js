@@ -1,18 +1,145 @@
-// path and named for label
-const supportedAlgos = {
-  "/ctph": "ssdeep",
-};
-const algoPaths = Object.keys(supportedAlgos);
+// path and named for label, populated from GET /algos
+var supportedAlgos = {};
+var algoInfos = {};
+const algoPaths = () => Object.keys(supportedAlgos);
+
+// Builds the algorithm dropdown from the server's registry
+function loadAlgos(done) {
+  $.ajax({
+    url: "algos",
+    type: "GET",
+    dataType: "json",
+  })
+  .fail(function (error) {
+    console.log("ajax failed: ", error);
+  })
+  .done(function (algos) {
+    $("#algo-list").empty();
+    algos.forEach((info) => {
+      let path = "/" + info.name;
+      supportedAlgos[path] = info.display_name;
+      algoInfos[path] = info;
+
+      $("<a>", { class: "navbar-item", href: path, title: info.description })
+        .attr("data-link", "")
+        .text(info.display_name)
+        .appendTo("#algo-list");
+    });
+    done();
+  });
+}
+
+// Shows the metadata of the selected algorithm and applies its input constraints
+function describeAlgo(path, useExample) {
+  let info = algoInfos[path];
+
+  $("#algo-name").text(supportedAlgos[path]);
+  $("#algo-name").attr("title", info.description || "");
+
+  if (info.reference) {
+    $("#algo-reference").attr("href", info.reference).removeClass("is-hidden");
+  } else {
+    $("#algo-reference").addClass("is-hidden");
+  }
+
+  if (info.input.max_length) {
+    $("#algo-input").attr("maxlength", info.input.max_length);
+  } else {
+    $("#algo-input").removeAttr("maxlength");
+  }
+
+  // only ssdeep signatures can be compared
+  $("#compare-section").toggleClass("is-hidden", path !== "/ctph");
+  loadOptions(path);
+
+  if (useExample && info.example) {
+    $("#algo-input").val(info.example);
+  }
+}
+
+// the options of the selected algorithm, populated from GET /{algo}/options
+var algoOptionsSchema = [];
+
+// Draws a form field for each option the algorithm takes, set to its default
+function loadOptions(path) {
+  algoOptionsSchema = [];
+  $("#algo-options").empty();
+  $("#options-section").addClass("is-hidden");
+
+  $.ajax({
+    url: `${path}/options`,
+    type: "GET",
+    async: false,
+    dataType: "json",
+  })
+  .fail(function (error) {
+    console.log("ajax failed: ", error);
+  })
+  .done(function (schema) {
+    algoOptionsSchema = schema.options;
+    schema.options.forEach((option) => {
+      let control = $("<div>", { class: "control" }).appendTo("#algo-options");
+      $("<label>", { class: "help", for: "option-" + option.name, title: option.help || "" })
+        .text(option.label || option.name)
+        .appendTo(control);
+
+      let input = $("<input>", { id: "option-" + option.name, title: option.help || "" });
+      switch (option.type) {
+        case "boolean":
+          input.attr("type", "checkbox").prop("checked", option.default === true);
+          break;
+        case "integer":
+          input.attr({ type: "number", class: "input option-number", min: option.min })
+            .val(option.default);
+          if (option.max) {
+            input.attr("max", option.max);
+          }
+          break;
+        default:
+          control.addClass("is-expanded");
+          input.attr({ type: "text", class: "input" }).val(option.default);
+          if (option.max) {
+            input.attr("maxlength", option.max);
+          }
+      }
+      input.appendTo(control);
+    });
+    $("#options-section").toggleClass("is-hidden", schema.options.length < 1);
+  });
+}
+
+// The options the algorithm is initialized with, undefined if it takes none
+function algoOptions() {
+  if (algoOptionsSchema.length < 1) {
+    return undefined;
+  }
+
+  let options = {};
+  algoOptionsSchema.forEach((option) => {
+    let input = $("#option-" + option.name);
+    switch (option.type) {
+      case "boolean":
+        options[option.name] = input.prop("checked");
+        break;
+      case "integer":
+        options[option.name] = Number(input.val());
+        break;
+      default:
+        options[option.name] = input.val();
+    }
+  });
+  return options;
+}
 
 // Wires in the algorithm implementation selected to load
 // https://stackoverflow.com/a/39695533/1120453
-function fetchApp() {
+function fetchApp(useExample) {
   var algo = () => {return localStorage.getItem("algoPathName")};
-  if (null === algo() || !algoPaths.includes(algo())){
+  if (null === algo() || !algoPaths().includes(algo())){
     // set to DEFAULT
-    localStorage.setItem("algoPathName", algoPaths[0]);
+    localStorage.setItem("algoPathName", algoPaths()[0]);
   }
-  $("#algo-name").text(supportedAlgos[algo()]);
+  describeAlgo(algo(), useExample);
 
   $.ajax({
     url: "fragments/app.html",
@@ -29,15 +156,16 @@ function fetchApp() {
 }
 
 $(document).ready((e) => {
-  $(".navbar-item").click((e) => {
+  // delegated, as the dropdown items are added once the registry is loaded
+  $("#algo-list").on("click", ".navbar-item", (e) => {
     if (e.target.matches("[data-link]")) {
       e.preventDefault();
       localStorage.setItem("algoPathName", e.target.pathname);
-      fetchApp();
+      fetchApp(true);
     }
   });
 
-  fetchApp();
+  loadAlgos(() => fetchApp(false));
 });
 
 // Bulma toggle for mobile hamburger menu
@@ -0,0 +1,147 @@
+package algoexplore
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TraceFormat - the version of the Trace format
+const TraceFormat = 1
+
+// Trace - a recorded run of an algorithm, everything needed to replay it
+// deterministically: how it was initialized and every byte it was stepped with.
+// Input is only set if the whole input was provided at init. States, if
+// recorded, holds the serialized state after init then after each step.
+type Trace struct {
+	Format      int             `json:"format"`
+	Algo        string          `json:"algo"`
+	Version     string          `json:"version,omitempty"`
+	InputLength int             `json:"input_length"`
+	Options     json.RawMessage `json:"options,omitempty"`
+	Input       []byte          `json:"input,omitempty"`
+	Steps       []byte          `json:"steps"`
+	States      []string        `json:"states,omitempty"`
+}
+
+// TraceMismatchError - a replayed state that differs from the one recorded
+// in the trace, Step is the number of steps taken, 0 being the state after init
+type TraceMismatchError struct {
+	Step     int
+	Recorded string
+	Replayed string
+}
+
+func (e *TraceMismatchError) Error() string {
+	return fmt.Sprintf("the state after step %d differs from the trace", e.Step)
+}
+
+// RecordTrace records the run of a plugin initialized with inputLen and opts,
+// then stepped with steps. With states the run is replayed on algo to record
+// the state after every step.
+func RecordTrace(algo AlgoPlugin, inputLen int, input []byte, opts json.RawMessage, steps []byte, states bool) (*Trace, error) {
+	t := &Trace{
+		Format:      TraceFormat,
+		Algo:        algo.Name(),
+		Version:     Describe(algo).Version,
+		InputLength: inputLen,
+		Options:     opts,
+		Input:       input,
+		Steps:       steps,
+	}
+	if !states {
+		return t, nil
+	}
+
+	t.States = make([]string, 0, len(steps)+1)
+	record := func(_ int, state string) error {
+		t.States = append(t.States, state)
+		return nil
+	}
+	if err := t.run(algo, false, record); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Replay initializes the plugin as the trace was then steps it with each of the
+// trace's bytes, leaving it in the final state. If visit is not nil it is called
+// with the state after init, step 0, then after each step as it is replayed, so
+// the states need not all be held at once, and an error from it stops the
+// replay. No step may follow the plugin being done and, if the trace has the
+// input, every step must be the input byte the plugin consumes next. If the
+// trace recorded its states, every replayed state must match, otherwise a
+// *TraceMismatchError is returned.
+func (t *Trace) Replay(algo AlgoPlugin, visit func(step int, state string) error) error {
+	if err := t.validate(algo); err != nil {
+		return err
+	}
+	return t.run(algo, len(t.States) > 0, visit)
+}
+
+// validate checks the trace is one the plugin can replay
+func (t *Trace) validate(algo AlgoPlugin) error {
+	switch version := Describe(algo).Version; {
+	case t.Format != TraceFormat:
+		return fmt.Errorf("unsupported trace format %d", t.Format)
+	case t.Algo != algo.Name():
+		return fmt.Errorf("the trace is of %s, not %s", t.Algo, algo.Name())
+	case t.Version != version:
+		return fmt.Errorf("the trace is of version %q of %s, not %q", t.Version, t.Algo, version)
+	case t.Input != nil && len(t.Input) != t.InputLength:
+		return fmt.Errorf("the input is %d bytes, not the input length of %d", len(t.Input), t.InputLength)
+	case len(t.States) > 0 && len(t.States) != len(t.Steps)+1:
+		return fmt.Errorf("the trace has %d states for %d steps, there must be one more", len(t.States), len(t.Steps))
+	}
+	return nil
+}
+
+func (t *Trace) run(algo AlgoPlugin, verify bool, visit func(step int, state string) error) error {
+	if err := InitAlgo(algo, t.InputLength, t.Options); err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		state, err := algo.SerializeState()
+		if err != nil {
+			return err
+		}
+		if verify && state != t.States[i] {
+			return &TraceMismatchError{Step: i, Recorded: t.States[i], Replayed: state}
+		}
+		if visit != nil {
+			if err := visit(i, state); err != nil {
+				return err
+			}
+		}
+
+		if i == len(t.Steps) {
+			return nil
+		}
+		if err := t.checkStep(algo, i); err != nil {
+			return err
+		}
+		if err := algo.Step(t.Steps[i]); err != nil {
+			return err
+		}
+	}
+}
+
+// checkStep checks the plugin takes step i, that is it is not done and, if the
+// trace has the input, the step is the input byte the plugin consumes next
+func (t *Trace) checkStep(algo AlgoPlugin, i int) error {
+	if algo.Done() {
+		return fmt.Errorf("%s is done after %d steps, the trace has %d", t.Algo, i, len(t.Steps))
+	}
+	if t.Input == nil {
+		return nil
+	}
+
+	d, ok := NextInput(algo, t.Input, i)
+	switch {
+	case !ok:
+		return fmt.Errorf("step %d is past the end of the input", i)
+	case d != t.Steps[i]:
+		return fmt.Errorf("step %d is %#x, not the input byte %#x", i, t.Steps[i], d)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package algoexplore
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecordTrace_withStates_ReplaysToTheSameStates(t *testing.T) {
+	trace, err := RecordTrace(&Summer{}, 3, []byte{1, 2, 3}, nil, []byte{1, 2, 3}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"0/0", "1/1", "3/2", "6/3"}
+	if diff := cmp.Diff(expected, trace.States); diff != "" {
+		t.Fatalf("unexpected recorded states (-want +got):\n%s", diff)
+	}
+
+	// a trace survives being saved
+	data, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Trace
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	algo := &Summer{}
+	var states []string
+	err = loaded.Replay(algo, func(step int, state string) error {
+		if step != len(states) {
+			t.Fatalf("expected step %d, got %d", len(states), step)
+		}
+		states = append(states, state)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, states); diff != "" {
+		t.Fatalf("unexpected replayed states (-want +got):\n%s", diff)
+	}
+	if serialized(algo) != "6/3" {
+		t.Fatalf("expected the algo to be left in the final state, got %s", serialized(algo))
+	}
+}
+
+func TestRecordTrace_withoutStates_OnlyRecordsTheRun(t *testing.T) {
+	trace, err := RecordTrace(&OptionsFake{}, 5, nil, json.RawMessage(`{"seed": 2}`), []byte{9}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &Trace{Format: TraceFormat, Algo: "fake", InputLength: 5,
+		Options: json.RawMessage(`{"seed": 2}`), Steps: []byte{9}}
+	if diff := cmp.Diff(expected, trace); diff != "" {
+		t.Fatalf("unexpected trace (-want +got):\n%s", diff)
+	}
+
+	algo := &OptionsFake{}
+	if err := trace.Replay(algo, nil); err != nil {
+		t.Fatal(err)
+	}
+	if algo.seed != 2 {
+		t.Fatalf("expected the replay to be initialized with the options, got seed %d", algo.seed)
+	}
+}
+
+func TestTrace_Replay_withChangedState_ReturnsMismatch(t *testing.T) {
+	trace, err := RecordTrace(&Summer{}, 3, nil, nil, []byte{1, 2, 3}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace.States[2] = "4/2"
+
+	err = trace.Replay(&Summer{}, nil)
+	m, ok := err.(*TraceMismatchError)
+	if !ok {
+		t.Fatalf("expected a mismatch, got %v", err)
+	}
+	if m.Step != 2 || m.Recorded != "4/2" || m.Replayed != "3/2" {
+		t.Fatalf("unexpected mismatch: %#v", m)
+	}
+}
+
+func TestTrace_Replay_withVisitError_StopsTheReplay(t *testing.T) {
+	trace := &Trace{Format: TraceFormat, Algo: "summer", InputLength: 3, Steps: []byte{1, 2, 3}}
+	stop := errors.New("stop")
+
+	algo := &Summer{}
+	err := trace.Replay(algo, func(step int, _ string) error {
+		if step == 1 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("expected the visit error, got %v", err)
+	}
+	if serialized(algo) != "1/1" {
+		t.Fatalf("expected no step after the visit failed, got %s", serialized(algo))
+	}
+}
+
+func TestTrace_Replay_withStepsOnceDone_ReturnsError(t *testing.T) {
+	trace := &Trace{Format: TraceFormat, Algo: "passer", InputLength: 1, Steps: []byte{1, 0, 1, 0, 1}}
+
+	if err := trace.Replay(&Passer{}, nil); err == nil {
+		t.Fatal("expected a step once done to be rejected")
+	}
+
+	trace.Steps = trace.Steps[:4]
+	if err := trace.Replay(&Passer{}, nil); err != nil {
+		t.Fatalf("expected the steps until done to replay, got %v", err)
+	}
+}
+
+func TestTrace_Replay_withStepsOtherThanTheInput_ReturnsError(t *testing.T) {
+	for name, trace := range map[string]*Trace{
+		"other byte": {Format: TraceFormat, Algo: "summer", InputLength: 3, Input: []byte("abc"),
+			Steps: []byte("abd")},
+		"past the input": {Format: TraceFormat, Algo: "summer", InputLength: 3, Input: []byte("abc"),
+			Steps: []byte("abca")},
+	} {
+		if err := trace.Replay(&Summer{}, nil); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	// a Positioner is checked against the byte at its position
+	trace := &Trace{Format: TraceFormat, Algo: "passer", InputLength: 3, Input: []byte("abc"),
+		Steps: []byte("abc\x00aaa")}
+	if err := trace.Replay(&Passer{}, nil); err == nil {
+		t.Fatal("expected the second pass to be checked against the input")
+	}
+
+	trace.Steps = []byte("abc\x00abc\x00")
+	if err := trace.Replay(&Passer{}, nil); err != nil {
+		t.Fatalf("expected the input stepped twice to replay, got %v", err)
+	}
+}
+
+func TestTrace_Replay_withInvalidTraces_ReturnsErrors(t *testing.T) {
+	valid := func() *Trace {
+		return &Trace{Format: TraceFormat, Algo: "summer", InputLength: 2, Steps: []byte{1, 2}}
+	}
+
+	for name, change := range map[string]func(t *Trace){
+		"unknown format":   func(t *Trace) { t.Format = 2 },
+		"other algo":       func(t *Trace) { t.Algo = "fake" },
+		"other version":    func(t *Trace) { t.Version = "2" },
+		"short input":      func(t *Trace) { t.Input = []byte{1} },
+		"too few states":   func(t *Trace) { t.States = []string{"0/0", "1/1"} },
+		"options for none": func(t *Trace) { t.Options = json.RawMessage(`{"seed": 1}`) },
+	} {
+		trace := valid()
+		change(trace)
+		if err := trace.Replay(&Summer{}, nil); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	if err := valid().Replay(&Summer{}, nil); err != nil {
+		t.Fatalf("expected the unchanged trace to replay, got %v", err)
+	}
+}